@@ -0,0 +1,8 @@
+package crdt
+
+// ElementGraph is the ElementGraphOf with []byte payloads on nodes and edges.
+type ElementGraph = ElementGraphOf[[]byte, []byte]
+
+func NewElementGraph(opts ...Option) *ElementGraph {
+	return NewElementGraphOf[[]byte, []byte](opts...)
+}
@@ -0,0 +1,225 @@
+package crdt
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/tauki/crdt/graph"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ViolationKind is the kind of inconsistency reported by Check.
+type ViolationKind int
+
+const (
+	// MalformedSet is a NodeSet or EdgeSet that would be refused by Merge,
+	// see ErrInvalidState.
+	MalformedSet ViolationKind = iota
+	// MissingNode is a node the sets say is live that is not in the graph.
+	MissingNode
+	// UnexpectedNode is a node in the graph that the sets say is not live.
+	UnexpectedNode
+	// StaleNode is a node whose payload in the graph differs from the one in
+	// the NodeSet.
+	StaleNode
+	// MissingEdge is an edge the sets say is in the graph that is not.
+	MissingEdge
+	// UnexpectedEdge is an edge in the graph that the sets say is not.
+	UnexpectedEdge
+	// StaleEdge is an edge whose endpoints, label or payload in the graph
+	// differ from the ones in the EdgeSet.
+	StaleEdge
+	// StaleDangling is an edge that is listed by DanglingEdges but should not
+	// be, or the other way around.
+	StaleDangling
+	// UnresolvedEndpoint is an edge in the graph whose endpoint is not a node
+	// of the graph, or is a copy of one.
+	UnresolvedEndpoint
+	// MisplacedEdge is an edge kept in the Edges of a node other than its
+	// From.
+	MisplacedEdge
+	// StaleIndex is an edge missing from, or left over in, the incoming edge
+	// index of the graph.
+	StaleIndex
+)
+
+func (k ViolationKind) String() string {
+	switch k {
+	case MalformedSet:
+		return "malformed set"
+	case MissingNode:
+		return "missing node"
+	case UnexpectedNode:
+		return "unexpected node"
+	case StaleNode:
+		return "stale node"
+	case MissingEdge:
+		return "missing edge"
+	case UnexpectedEdge:
+		return "unexpected edge"
+	case StaleEdge:
+		return "stale edge"
+	case StaleDangling:
+		return "stale dangling edge"
+	case UnresolvedEndpoint:
+		return "unresolved endpoint"
+	case MisplacedEdge:
+		return "misplaced edge"
+	case StaleIndex:
+		return "stale index"
+	}
+	return "unknown"
+}
+
+// Violation is an inconsistency found by Check in the node or edge with the
+// given id.
+type Violation struct {
+	Kind   ViolationKind
+	ID     uuid.UUID
+	Detail string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s %s: %s", v.Kind, v.ID, v.Detail)
+}
+
+// Check audits the ElementGraph and returns the violations found, ordered by
+// kind and id, or none if it is consistent. It compares the graph with the
+// one materialized from the sets, and checks that the edges of the graph
+// resolve to its nodes, are kept by their From node and are indexed by their
+// To node. It does not change the ElementGraph.
+func (s *ElementGraphOf[N, E]) Check() []Violation {
+	c := &checker[N, E]{}
+
+	if err := validate(s); err != nil {
+		c.add(MalformedSet, uuid.Nil, err.Error())
+		return c.violations
+	}
+
+	c.compare(s, s.materialize(time.Time{}))
+	c.structure(s.Graph)
+
+	sort.SliceStable(c.violations, func(i, j int) bool {
+		a, b := c.violations[i], c.violations[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return bytes.Compare(a.ID[:], b.ID[:]) < 0
+	})
+	return c.violations
+}
+
+type checker[N, E any] struct {
+	violations []Violation
+}
+
+func (c *checker[N, E]) add(kind ViolationKind, id uuid.UUID, format string, args ...interface{}) {
+	c.violations = append(c.violations, Violation{Kind: kind, ID: id, Detail: fmt.Sprintf(format, args...)})
+}
+
+// compare reports the differences between the graph of s and the graph
+// materialized from its sets.
+func (c *checker[N, E]) compare(s *ElementGraphOf[N, E], m materialized[N, E]) {
+	for _, want := range m.graph.Nodes() {
+		got := s.Graph.GetNode(want.ID)
+		switch {
+		case got == nil:
+			c.add(MissingNode, want.ID, "live in the node set but not in the graph")
+		case !reflect.DeepEqual(got.Payload, want.Payload):
+			c.add(StaleNode, want.ID, "payload differs from the node set")
+		}
+	}
+	for _, got := range s.Graph.Nodes() {
+		if m.graph.GetNode(got.ID) == nil {
+			c.add(UnexpectedNode, got.ID, "in the graph but not live in the node set")
+		}
+	}
+
+	wantEdges := edgesByID(m.graph.Edges())
+	gotEdges := edgesByID(s.Graph.Edges())
+	for id, want := range wantEdges {
+		got, ok := gotEdges[id]
+		switch {
+		case !ok:
+			c.add(MissingEdge, id, "live in the edge set but not in the graph")
+		case got.From.ID != want.From.ID || got.To.ID != want.To.ID:
+			c.add(StaleEdge, id, "connects %s to %s, the edge set says %s to %s",
+				got.From.ID, got.To.ID, want.From.ID, want.To.ID)
+		case got.Undirected != want.Undirected || got.Label != want.Label ||
+			!reflect.DeepEqual(got.Payload, want.Payload):
+			c.add(StaleEdge, id, "label, direction or payload differs from the edge set")
+		}
+	}
+	for id := range gotEdges {
+		if _, ok := wantEdges[id]; !ok {
+			c.add(UnexpectedEdge, id, "in the graph but not live in the edge set, or its endpoints are not")
+		}
+	}
+
+	for id := range m.dangling {
+		if _, ok := s.dangling[id]; !ok {
+			c.add(StaleDangling, id, "dangling but not listed by DanglingEdges")
+		}
+	}
+	for id := range s.dangling {
+		if _, ok := m.dangling[id]; !ok {
+			c.add(StaleDangling, id, "listed by DanglingEdges but not dangling")
+		}
+	}
+}
+
+// structure reports edges of the graph that do not resolve to its nodes or
+// are out of place in its adjacency lists.
+func (c *checker[N, E]) structure(g graph.GraphOf[N, E]) {
+	for _, n := range g.Nodes() {
+		for k, e := range n.Edges {
+			switch {
+			case e.From == nil || e.To == nil:
+				c.add(UnresolvedEndpoint, k, "has no endpoint")
+				continue
+			case e.From.ID != n.ID:
+				c.add(MisplacedEdge, k, "kept by node %s but starts at %s", n.ID, e.From.ID)
+			case e.From != n:
+				c.add(UnresolvedEndpoint, k, "starts at a copy of %s, not at the node of the graph", n.ID)
+			}
+
+			to := g.GetNode(e.To.ID)
+			if to == nil {
+				c.add(UnresolvedEndpoint, k, "points to %s, which is not in the graph", e.To.ID)
+				continue
+			}
+			if to != e.To {
+				c.add(UnresolvedEndpoint, k, "points to a copy of %s, not to the node of the graph", e.To.ID)
+			}
+			if _, ok := g.InEdges(e.To)[k]; !ok {
+				c.add(StaleIndex, k, "not in the incoming edges of %s", e.To.ID)
+			}
+		}
+
+		for k, e := range g.InEdges(n) {
+			if e.From == nil || e.To == nil {
+				c.add(UnresolvedEndpoint, k, "has no endpoint")
+				continue
+			}
+
+			from := g.GetNode(e.From.ID)
+			if from == nil {
+				c.add(UnresolvedEndpoint, k, "starts at %s, which is not in the graph", e.From.ID)
+				continue
+			}
+			if _, ok := from.Edges[k]; !ok || e.To.ID != n.ID {
+				c.add(StaleIndex, k, "indexed as incoming edge of %s but not in the graph", n.ID)
+			}
+		}
+	}
+}
+
+func edgesByID[N, E any](edges []*graph.EdgeOf[N, E]) map[uuid.UUID]*graph.EdgeOf[N, E] {
+	set := make(map[uuid.UUID]*graph.EdgeOf[N, E], len(edges))
+	for _, e := range edges {
+		set[e.ID] = e
+	}
+	return set
+}
@@ -0,0 +1,170 @@
+package crdt
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/tauki/crdt/graph"
+	"github.com/tauki/crdt/twoPSet"
+	"testing"
+	"time"
+)
+
+func newCheckedGraph(opts ...Option) (*ElementGraph, *graph.Node, *graph.Node, *graph.Edge) {
+	g := NewElementGraph(opts...)
+	node1 := graph.NewNode(uuid.New(), []byte("a"))
+	node2 := graph.NewNode(uuid.New(), []byte("b"))
+	g.AddNode(node1)
+	g.AddNode(node2)
+	edge := graph.NewEdge(uuid.New(), node1, node2)
+	g.AddEdge(edge)
+	return g, node1, node2, edge
+}
+
+func TestElementGraph_Check_Consistent(t *testing.T) {
+	for _, policy := range []EdgePolicy{KeepDangling, AddWins, RemoveWins} {
+		g1, node1, node2, _ := newCheckedGraph(WithEdgePolicy(policy))
+		g2 := NewElementGraph(WithEdgePolicy(policy))
+		assert.NoError(t, g2.Merge(g1))
+
+		g1.RemoveNode(node2)
+		time.Sleep(1)
+		g2.AddEdge(graph.NewEdge(uuid.New(), g2.Graph.GetNode(node2.ID), g2.Graph.GetNode(node1.ID)))
+
+		assert.NoError(t, g1.Merge(g2))
+		assert.NoError(t, g2.Merge(g1))
+		assert.Empty(t, g1.Check(), policy)
+		assert.Empty(t, g2.Check(), policy)
+	}
+}
+
+func TestElementGraph_Check_Violations(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(g *ElementGraph, node1, node2 *graph.Node, edge *graph.Edge)
+		kind    ViolationKind
+	}{
+		{
+			name: "malformed set",
+			corrupt: func(g *ElementGraph, node1, node2 *graph.Node, edge *graph.Edge) {
+				g.NodeSet.GetAddSet()[uuid.New()] = twoPSet.OPOf[*graph.Node]{Timestamp: time.Now()}
+			},
+			kind: MalformedSet,
+		},
+		{
+			name: "missing node",
+			corrupt: func(g *ElementGraph, node1, node2 *graph.Node, edge *graph.Edge) {
+				g.Graph.(*graph.T).RemoveNode(node2)
+			},
+			kind: MissingNode,
+		},
+		{
+			name: "unexpected node",
+			corrupt: func(g *ElementGraph, node1, node2 *graph.Node, edge *graph.Edge) {
+				g.Graph.AddNode(graph.NewNode(uuid.New(), nil))
+			},
+			kind: UnexpectedNode,
+		},
+		{
+			name: "stale node",
+			corrupt: func(g *ElementGraph, node1, node2 *graph.Node, edge *graph.Edge) {
+				g.NodeSet.Add(node1.ID, graph.NewNode(node1.ID, []byte("changed")))
+			},
+			kind: StaleNode,
+		},
+		{
+			name: "missing edge",
+			corrupt: func(g *ElementGraph, node1, node2 *graph.Node, edge *graph.Edge) {
+				g.Graph.RemoveEdge(edge)
+			},
+			kind: MissingEdge,
+		},
+		{
+			name: "unexpected edge",
+			corrupt: func(g *ElementGraph, node1, node2 *graph.Node, edge *graph.Edge) {
+				_ = g.EdgeSet.Remove(edge.ID)
+			},
+			kind: UnexpectedEdge,
+		},
+		{
+			name: "misplaced edge",
+			corrupt: func(g *ElementGraph, node1, node2 *graph.Node, edge *graph.Edge) {
+				node2.Edges[edge.ID] = edge
+			},
+			kind: MisplacedEdge,
+		},
+		{
+			name: "edge from a copy of its node",
+			corrupt: func(g *ElementGraph, node1, node2 *graph.Node, edge *graph.Edge) {
+				g.Graph.GetNode(node1.ID).Edges[edge.ID].From = graph.NewNode(node1.ID, node1.Payload)
+			},
+			kind: UnresolvedEndpoint,
+		},
+		{
+			name: "edge to a copy of its node",
+			corrupt: func(g *ElementGraph, node1, node2 *graph.Node, edge *graph.Edge) {
+				g.Graph.GetNode(node1.ID).Edges[edge.ID].To = graph.NewNode(node2.ID, node2.Payload)
+			},
+			kind: UnresolvedEndpoint,
+		},
+		{
+			name: "stale index",
+			corrupt: func(g *ElementGraph, node1, node2 *graph.Node, edge *graph.Edge) {
+				delete(g.Graph.(*graph.T).In[node2.ID], edge.ID)
+			},
+			kind: StaleIndex,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, node1, node2, edge := newCheckedGraph()
+			assert.Empty(t, g.Check())
+
+			tt.corrupt(g, node1, node2, edge)
+			violations := g.Check()
+			if assert.NotEmpty(t, violations) {
+				assert.Equal(t, tt.kind, violations[0].Kind, violations)
+			}
+		})
+	}
+}
+
+func TestElementGraph_Check_EdgesPointAtGraphNodes(t *testing.T) {
+	g, node1, node2, _ := newCheckedGraph()
+
+	// the endpoints of the edge are copies of the nodes of the graph
+	edge := graph.NewEdge(uuid.New(), graph.NewNode(node2.ID, nil), graph.NewNode(node1.ID, nil))
+	g.AddEdge(edge)
+	assert.Empty(t, g.Check())
+	added := g.Graph.GetNode(node2.ID).Edges[edge.ID]
+	assert.Same(t, g.Graph.GetNode(node1.ID), added.To)
+
+	g.RegenerateGraph()
+	assert.Empty(t, g.Check())
+	for _, e := range g.Graph.Edges() {
+		assert.Same(t, g.Graph.GetNode(e.From.ID), e.From)
+		assert.Same(t, g.Graph.GetNode(e.To.ID), e.To)
+	}
+}
+
+func TestElementGraph_Check_StaleDangling(t *testing.T) {
+	g, _, node2, _ := newCheckedGraph()
+	g2 := NewElementGraph()
+	assert.NoError(t, g2.Merge(g))
+
+	g.RemoveNode(node2)
+	time.Sleep(1)
+	edge := graph.NewEdge(uuid.New(), g2.Graph.GetNode(node2.ID), g2.Graph.GetNode(node2.ID))
+	g2.AddEdge(edge)
+
+	g.NodeSet.Merge(g2.NodeSet)
+	g.EdgeSet.Merge(g2.EdgeSet)
+	assert.Equal(t, []Violation{{
+		Kind:   StaleDangling,
+		ID:     edge.ID,
+		Detail: "dangling but not listed by DanglingEdges",
+	}}, g.Check())
+
+	g.RegenerateGraph()
+	assert.Empty(t, g.Check())
+}
@@ -0,0 +1,23 @@
+package crdt
+
+import (
+	"crypto/sha256"
+	"github.com/tauki/crdt/graph"
+	"github.com/tauki/crdt/twoPSet"
+)
+
+// Digest returns a hash of the CRDT state of the ElementGraph, the operations
+// in its NodeSet and EdgeSet. Replicas that converged have the same digest,
+// no matter in which order they merged. It is combined from the digests kept
+// by the sets, see twoPSet.TOf.Digest, so it is cheap to call after every
+// change.
+func (s *ElementGraphOf[N, E]) Digest() twoPSet.Digest {
+	nodes, edges := s.NodeSet.Digest(), s.EdgeSet.Digest()
+	return sha256.Sum256(append(nodes[:], edges[:]...))
+}
+
+// GraphDigest returns a hash of the materialized graph, including payloads,
+// see graph.Digest. It visits the whole graph.
+func (s *ElementGraphOf[N, E]) GraphDigest() twoPSet.Digest {
+	return graph.Digest(s.Graph)
+}
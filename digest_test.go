@@ -0,0 +1,43 @@
+package crdt
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/tauki/crdt/graph"
+	"testing"
+	"time"
+)
+
+func TestElementGraph_Digest(t *testing.T) {
+	g1 := NewElementGraph()
+	g2 := NewElementGraph()
+	g3 := NewElementGraph()
+	assert.Equal(t, g1.Digest(), g2.Digest())
+	assert.Equal(t, g1.GraphDigest(), g2.GraphDigest())
+
+	node1 := graph.NewNode(uuid.New(), []byte("a"))
+	node2 := graph.NewNode(uuid.New(), []byte("b"))
+	g1.AddNode(node1)
+	g1.AddNode(node2)
+	g1.AddEdge(graph.NewEdge(uuid.New(), node1, node2))
+	time.Sleep(1)
+	g2.AddNode(graph.NewNode(uuid.New(), []byte("c")))
+	time.Sleep(1)
+	g3.AddNode(graph.NewNode(node2.ID, []byte("d")))
+	assert.NotEqual(t, g1.Digest(), g2.Digest())
+
+	before := g1.Digest()
+	g1.RemoveNode(node2)
+	assert.NotEqual(t, before, g1.Digest())
+
+	assert.NoError(t, g1.Merge(g2))
+	assert.NoError(t, g1.Merge(g3))
+	assert.NoError(t, g3.Merge(g2))
+	assert.NoError(t, g3.Merge(g1))
+	assert.NoError(t, g2.Merge(g3))
+
+	for _, g := range []*ElementGraph{g2, g3} {
+		assert.Equal(t, g1.Digest(), g.Digest())
+		assert.Equal(t, g1.GraphDigest(), g.GraphDigest())
+	}
+}
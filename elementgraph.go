@@ -1,89 +1,753 @@
 package crdt
 
 import (
+	"bytes"
+	"github.com/google/uuid"
 	"github.com/tauki/crdt/graph"
 	"github.com/tauki/crdt/twoPSet"
+	"sort"
+	"time"
 )
 
-type ElementGraph struct {
-	NodeSet twoPSet.TwoPSet
-	EdgeSet twoPSet.TwoPSet
-	Graph   graph.Graph
+// EdgePolicy decides what happens to a live edge whose endpoint is not live,
+// which happens when one replica adds an edge while another removes one of
+// its nodes.
+type EdgePolicy int
+
+const (
+	// KeepDangling leaves the edge live in the EdgeSet and out of the graph,
+	// it can be listed with DanglingEdges.
+	KeepDangling EdgePolicy = iota
+	// AddWins revives the removed endpoint so the edge stays in the graph.
+	AddWins
+	// RemoveWins tombstones the edge in the EdgeSet.
+	RemoveWins
+)
+
+type config struct {
+	replica       uuid.UUID
+	history       bool
+	deterministic bool
+	undirected    bool
+	simple        bool
+	edgePolicy    EdgePolicy
+	acyclic       bool
+	reachability  bool
+	now           func() time.Time
+}
+
+type Option func(*config)
+
+func WithEdgePolicy(policy EdgePolicy) Option {
+	return func(c *config) {
+		c.edgePolicy = policy
+	}
+}
+
+// WithReplicaID sets the id recorded as the origin of the local operations,
+// a random id is used otherwise.
+func WithReplicaID(id uuid.UUID) Option {
+	return func(c *config) {
+		c.replica = id
+	}
+}
+
+// WithClock sets the clock the operations on nodes and edges are timestamped
+// with, see twoPSet.WithClock.
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+// WithHistory records every operation on nodes and edges, see History.
+func WithHistory() Option {
+	return func(c *config) {
+		c.history = true
+	}
+}
+
+// WithDeterministicOrder makes the graph and the queries of the ElementGraph
+// visit nodes and edges ordered by id, see graph.WithDeterministicOrder.
+func WithDeterministicOrder() Option {
+	return func(c *config) {
+		c.deterministic = true
+	}
+}
+
+// WithUndirectedEdges makes every edge of the graph undirected, see
+// graph.WithUndirectedEdges. Single edges can be made undirected with
+// graph.NewUndirectedEdge instead.
+func WithUndirectedEdges() Option {
+	return func(c *config) {
+		c.undirected = true
+	}
+}
+
+// WithSimpleGraph identifies edges by their endpoints and label instead of
+// their id: AddEdge replaces the id of the edge with the one derived by
+// graph.EdgeID, so identical edges added concurrently by different replicas
+// are the same element after Merge. The graph refuses parallel edges, see
+// graph.WithSimpleGraph.
+func WithSimpleGraph() Option {
+	return func(c *config) {
+		c.simple = true
+	}
+}
+
+// WithAcyclic keeps the graph free of cycles. Edges that would close a cycle
+// are refused by AddEdge, and when replicas together form a cycle on Merge,
+// the edges added last are left out of the graph and reported by
+// SuppressedEdges. The edges stay live in the EdgeSet. Undirected edges are
+// cycles on their own, so they are always refused and suppressed, and an
+// acyclic graph WithUndirectedEdges has no edges.
+func WithAcyclic() Option {
+	return func(c *config) {
+		c.acyclic = true
+	}
+}
+
+// WithReachabilityIndex keeps a graph.ReachabilityIndex of the graph up to
+// date, see Reachability.
+func WithReachabilityIndex() Option {
+	return func(c *config) {
+		c.reachability = true
+	}
+}
+
+// ElementGraphOf is a replicated graph whose nodes carry payloads of type N
+// and whose edges carry payloads of type E.
+type ElementGraphOf[N, E any] struct {
+	NodeSet twoPSet.TwoPSetOf[*graph.NodeOf[N, E]]
+	EdgeSet twoPSet.TwoPSetOf[*graph.EdgeOf[N, E]]
+	Graph   graph.GraphOf[N, E]
+
+	config
+	dangling   graph.EdgeSetOf[N, E]
+	suppressed graph.EdgeSetOf[N, E]
+	reach      *graph.ReachabilityIndexOf[N, E]
+	undo       []operation[N, E]
+	redo       []operation[N, E]
+}
+
+func NewElementGraphOf[N, E any](opts ...Option) *ElementGraphOf[N, E] {
+	s := &ElementGraphOf[N, E]{
+		config:     config{replica: uuid.New()},
+		dangling:   make(graph.EdgeSetOf[N, E]),
+		suppressed: make(graph.EdgeSetOf[N, E]),
+	}
+
+	for _, opt := range opts {
+		opt(&s.config)
+	}
+
+	s.Graph = s.newGraph()
+	if s.reachability {
+		s.reach = graph.NewReachabilityIndex(s.Graph)
+	}
+
+	setOpts := []twoPSet.Option{twoPSet.WithReplica(s.replica)}
+	if s.history {
+		setOpts = append(setOpts, twoPSet.WithHistory())
+	}
+	if s.now != nil {
+		setOpts = append(setOpts, twoPSet.WithClock(s.now))
+	}
+	s.NodeSet = twoPSet.NewOf[*graph.NodeOf[N, E]](setOpts...)
+	s.EdgeSet = twoPSet.NewOf[*graph.EdgeOf[N, E]](setOpts...)
+
+	return s
+}
+
+func (s *ElementGraphOf[N, E]) ReplicaID() uuid.UUID {
+	return s.replica
+}
+
+func (s *ElementGraphOf[N, E]) EdgePolicy() EdgePolicy {
+	return s.edgePolicy
+}
+
+// Reachability returns the reachability index of the graph, which is updated
+// on every change and merge, or nil if the ElementGraph was created without
+// WithReachabilityIndex.
+func (s *ElementGraphOf[N, E]) Reachability() *graph.ReachabilityIndexOf[N, E] {
+	return s.reach
+}
+
+// DanglingEdges returns the live edges that are missing from the graph because
+// one of their endpoints is not live. They can be removed with RemoveEdge.
+func (s *ElementGraphOf[N, E]) DanglingEdges() []*graph.EdgeOf[N, E] {
+	return s.edgeList(s.dangling)
 }
 
-func NewElementGraph() *ElementGraph {
-	return &ElementGraph{
-		NodeSet: twoPSet.New(),
-		EdgeSet: twoPSet.New(),
-		Graph:   graph.New(),
+// SuppressedEdges returns the live edges that were left out of the graph to
+// keep it acyclic, see WithAcyclic.
+func (s *ElementGraphOf[N, E]) SuppressedEdges() []*graph.EdgeOf[N, E] {
+	return s.edgeList(s.suppressed)
+}
+
+func (s *ElementGraphOf[N, E]) edgeList(set graph.EdgeSetOf[N, E]) []*graph.EdgeOf[N, E] {
+	edges := make([]*graph.EdgeOf[N, E], 0, len(set))
+	for _, edge := range set {
+		edges = append(edges, edge)
+	}
+
+	if s.deterministic {
+		return graph.SortEdges(edges)
+	}
+	return edges
+}
+
+func (s *ElementGraphOf[N, E]) AddNode(node *graph.NodeOf[N, E]) {
+	if s.addNode(node) {
+		s.record(operation[N, E]{kind: opAddNode, node: node})
+	}
+}
+
+// UpdateNode replaces the payload of a node that is in the graph. It returns
+// false if the node does not exist. The node of the graph is replaced by a
+// new one holding the payload and the edges of the old one, the old node is
+// left unchanged for whoever still holds it.
+func (s *ElementGraphOf[N, E]) UpdateNode(node *graph.NodeOf[N, E]) bool {
+	existing := s.Graph.GetNode(node.ID)
+	if existing == nil {
+		return false
+	}
+
+	edges := s.incidentEdges(existing)
+	s.Graph.RemoveNode(existing)
+	s.Graph.AddNode(graph.NewNodeOf[N, E](node.ID, node.Payload))
+	for _, edge := range edges {
+		s.Graph.AddEdge(bind(s.Graph, edge))
+	}
+
+	s.NodeSet.Add(node.ID, graph.NewNodeOf[N, E](node.ID, node.Payload))
+	return true
+}
+
+// History returns the recorded operations on the node or edge with the given
+// id, oldest first. It is empty unless the graph was created WithHistory. The
+// payloads of the events are the *graph.NodeOf or *graph.EdgeOf of the
+// element, see NodeHistory and EdgeHistory for typed events.
+func (s *ElementGraphOf[N, E]) History(id uuid.UUID) []twoPSet.Event {
+	events := make([]twoPSet.Event, 0)
+	events = appendEvents(events, s.NodeHistory(id))
+	events = appendEvents(events, s.EdgeHistory(id))
+	return events
+}
+
+// NodeHistory returns the recorded operations on the node with the given id,
+// oldest first.
+func (s *ElementGraphOf[N, E]) NodeHistory(id uuid.UUID) []twoPSet.EventOf[*graph.NodeOf[N, E]] {
+	return s.NodeSet.GetHistory()[id]
+}
+
+// EdgeHistory returns the recorded operations on the edge with the given id,
+// oldest first.
+func (s *ElementGraphOf[N, E]) EdgeHistory(id uuid.UUID) []twoPSet.EventOf[*graph.EdgeOf[N, E]] {
+	return s.EdgeSet.GetHistory()[id]
+}
+
+func appendEvents[V any](events []twoPSet.Event, typed []twoPSet.EventOf[V]) []twoPSet.Event {
+	for _, e := range typed {
+		events = append(events, twoPSet.Event{
+			OPOf: twoPSet.OP{Payload: e.Payload, Timestamp: e.Timestamp, Replica: e.Replica},
+			Kind: e.Kind,
+		})
+	}
+	return events
+}
+
+// AddEdge adds the edge and reports whether it was added. It returns false if
+// the edge exists, one of its endpoints is not in the graph, or the graph
+// refuses it, see WithAcyclic and WithSimpleGraph.
+func (s *ElementGraphOf[N, E]) AddEdge(edge *graph.EdgeOf[N, E]) bool {
+	if s.simple {
+		key := *edge
+		key.Undirected = key.Undirected || s.undirected
+		edge.ID = key.Key()
+	}
+
+	if s.addEdge(edge) {
+		s.record(operation[N, E]{kind: opAddEdge, edge: edge})
+		return true
 	}
+	return false
 }
 
-func (s *ElementGraph) AddNode(node *graph.Node) {
+// RemoveNode removes the node together with the edges attached to it. The
+// attached edges are tombstoned as well, so that only edges the remover has
+// not seen are left to the EdgePolicy after a merge.
+func (s *ElementGraphOf[N, E]) RemoveNode(node *graph.NodeOf[N, E]) {
+	if removed, edges, ok := s.removeNode(node); ok {
+		s.record(operation[N, E]{kind: opRemoveNode, node: removed, edges: edges})
+	}
+}
+
+func (s *ElementGraphOf[N, E]) RemoveEdge(edge *graph.EdgeOf[N, E]) {
+	if s.removeEdge(edge) {
+		s.record(operation[N, E]{kind: opRemoveEdge, edge: edge})
+	}
+}
+
+func (s *ElementGraphOf[N, E]) addNode(node *graph.NodeOf[N, E]) bool {
 	if s.Graph.AddNode(node) {
-		s.NodeSet.Add(node.ID, node)
+		// the set keeps a copy, so the recorded operation does not change
+		// with the node of the caller or of the graph
+		s.NodeSet.Add(node.ID, graph.NewNodeOf[N, E](node.ID, node.Payload))
+		s.attachDangling(node)
+		return true
+	}
+	return false
+}
+
+// attachDangling resolves the dangling edges of a node added to the graph
+// the way materialize would: edges whose endpoints are both in the graph are
+// added to it, and edges whose other endpoint was removed are left to the
+// EdgePolicy. In acyclic and simple graphs, which edges make it in depends on
+// the order materialize adds them in, so the graph is regenerated instead.
+func (s *ElementGraphOf[N, E]) attachDangling(node *graph.NodeOf[N, E]) {
+	removeSet := s.NodeSet.GetRemoveSet()
+	edges := make([]*graph.EdgeOf[N, E], 0)
+	for _, edge := range s.edgeList(s.dangling) {
+		if edge.From.ID != node.ID && edge.To.ID != node.ID {
+			continue
+		}
+		if s.bothEnds(edge) || s.resolveDangling(s.Graph, edge, removeSet, time.Time{}) != KeepDangling {
+			edges = append(edges, edge)
+		}
+	}
+	if len(edges) == 0 {
+		return
+	}
+
+	if s.acyclic || s.simple {
+		s.RegenerateGraph()
+		return
+	}
+
+	for _, edge := range edges {
+		delete(s.dangling, edge.ID)
+		if !s.bothEnds(edge) {
+			if s.edgePolicy == RemoveWins {
+				_ = s.EdgeSet.Remove(edge.ID)
+				continue
+			}
+			for _, n := range []*graph.NodeOf[N, E]{edge.From, edge.To} {
+				if _, ok := removeSet[n.ID]; ok {
+					s.reviveNode(s.Graph, n)
+				}
+			}
+			s.invalidateReachability()
+		}
+
+		if s.Graph.AddEdge(bind(s.Graph, edge)) {
+			s.reachEdge(edge)
+		}
 	}
 }
 
-func (s *ElementGraph) AddEdge(edge *graph.Edge) {
-	if s.Graph.AddEdge(edge) {
+func (s *ElementGraphOf[N, E]) bothEnds(edge *graph.EdgeOf[N, E]) bool {
+	return s.Graph.GetNode(edge.From.ID) != nil && s.Graph.GetNode(edge.To.ID) != nil
+}
+
+func (s *ElementGraphOf[N, E]) addEdge(edge *graph.EdgeOf[N, E]) bool {
+	if s.acyclic && s.closesCycle(s.Graph, edge) {
+		return false
+	}
+
+	if s.Graph.AddEdge(bind(s.Graph, edge)) {
 		s.EdgeSet.Add(edge.ID, edge)
+		s.reachEdge(edge)
+		return true
 	}
+	return false
 }
 
-func (s *ElementGraph) RemoveNode(node *graph.Node) {
+// removeNode returns the removed node as it was in the graph, and the edges
+// that were removed with it.
+func (s *ElementGraphOf[N, E]) removeNode(node *graph.NodeOf[N, E]) (*graph.NodeOf[N, E], []*graph.EdgeOf[N, E], bool) {
+	existing := s.Graph.GetNode(node.ID)
+	edges := s.incidentEdges(node)
+
 	if s.Graph.RemoveNode(node) {
 		if err := s.NodeSet.Remove(node.ID); err != nil {
-			s.Graph.AddNode(node)
-			for _, v := range node.Edges {
+			s.Graph.AddNode(existing)
+			for _, v := range edges {
 				s.Graph.AddEdge(v)
 			}
+			return nil, nil, false
+		}
+
+		for _, v := range edges {
+			_ = s.EdgeSet.Remove(v.ID)
 		}
+		s.invalidateReachability()
+		s.releaseSuppressed()
+		return existing, edges, true
 	}
+
+	return nil, nil, false
 }
 
-func (s *ElementGraph) RemoveEdge(edge *graph.Edge) {
+// removeEdge removes the edge from the graph, or from the dangling edges, and
+// tombstones it in the EdgeSet.
+func (s *ElementGraphOf[N, E]) removeEdge(edge *graph.EdgeOf[N, E]) bool {
+	if _, ok := s.dangling[edge.ID]; ok {
+		if err := s.EdgeSet.Remove(edge.ID); err != nil {
+			return false
+		}
+		delete(s.dangling, edge.ID)
+		return true
+	}
+
 	if s.Graph.RemoveEdge(edge) {
 		if err := s.EdgeSet.Remove(edge.ID); err != nil {
-			s.Graph.AddEdge(edge)
+			s.Graph.AddEdge(bind(s.Graph, edge))
+			return false
 		}
+		s.invalidateReachability()
+		s.releaseSuppressed()
+		return true
 	}
+	return false
 }
 
-func (s *ElementGraph) Merge(g *ElementGraph) {
+// releaseSuppressed regenerates the graph after a removal when edges were
+// left out to keep it acyclic. The removal may let them back in, and which
+// ones depends on the order materialize adds the edges in.
+func (s *ElementGraphOf[N, E]) releaseSuppressed() {
+	if len(s.suppressed) > 0 {
+		s.RegenerateGraph()
+	}
+}
+
+// Merge merges the sets of g into s and regenerates the graph. The sets of g
+// are validated first, if they are malformed an error wrapping
+// ErrInvalidState is returned and s is left untouched.
+func (s *ElementGraphOf[N, E]) Merge(g *ElementGraphOf[N, E]) error {
+	if err := validate(g); err != nil {
+		return err
+	}
+
 	s.NodeSet.Merge(g.NodeSet)
 	s.EdgeSet.Merge(g.EdgeSet)
 	s.RegenerateGraph()
+	return nil
 }
 
-func (s *ElementGraph) RegenerateGraph() {
-	s.Graph = graph.New()
+// Fork returns an independent replica with the same state, e.g. to try out
+// changes that are later merged back with Merge or thrown away. The fork gets
+// a new replica id, so its operations are ordered apart from the ones of s.
+// The sets of the fork share their maps with s until either side changes
+// them, the graph is copied. The fork starts with empty undo and redo stacks.
+func (s *ElementGraphOf[N, E]) Fork() *ElementGraphOf[N, E] {
+	f := &ElementGraphOf[N, E]{config: s.config}
+	f.replica = uuid.New()
+	f.NodeSet = s.NodeSet.Fork(twoPSet.WithReplica(f.replica))
+	f.EdgeSet = s.EdgeSet.Fork(twoPSet.WithReplica(f.replica))
 
-	addSet := s.NodeSet.GetAddSet()
-	removeSet := s.NodeSet.GetRemoveSet()
+	g, ok := s.Graph.(*graph.TOf[N, E])
+	if !ok {
+		if f.reachability {
+			f.reach = &graph.ReachabilityIndexOf[N, E]{}
+		}
+		f.RegenerateGraph()
+		return f
+	}
 
-	for k, v := range addSet {
-		removedNode, ok := removeSet[k]
-		if ok {
-			if removedNode.Timestamp.After(v.Timestamp) {
+	f.Graph = g.Copy()
+	f.dangling = make(graph.EdgeSetOf[N, E], len(s.dangling))
+	for id, edge := range s.dangling {
+		f.dangling[id] = edge
+	}
+	f.suppressed = make(graph.EdgeSetOf[N, E], len(s.suppressed))
+	for id, edge := range s.suppressed {
+		f.suppressed[id] = edge
+	}
+	if f.reachability {
+		f.reach = graph.NewReachabilityIndex(f.Graph)
+	}
+	return f
+}
+
+func (s *ElementGraphOf[N, E]) RegenerateGraph() {
+	m := s.materialize(time.Time{})
+
+	for _, edge := range m.tombstones {
+		_ = s.EdgeSet.Remove(edge.ID)
+	}
+
+	s.Graph = m.graph
+	s.dangling = m.dangling
+	s.suppressed = m.suppressed
+	if s.reach != nil {
+		s.reach.Reset(s.Graph)
+	}
+}
+
+// AsOf materializes the graph as it was at the given time, using the
+// timestamps of the operations in NodeSet and EdgeSet. The returned graph is a
+// copy and is not kept in sync with the ElementGraph.
+//
+// Without WithHistory the sets only keep the latest add and remove of every
+// element, so the result is only right for elements whose add was not
+// replaced after the given time. An element updated with UpdateNode, re-added,
+// or added again by a replica merged in later, is missing from the result,
+// together with its edges. With WithHistory the full history is used instead
+// and the result is exact.
+func (s *ElementGraphOf[N, E]) AsOf(t time.Time) *graph.TOf[N, E] {
+	return s.materialize(t).graph
+}
+
+// materialized is the result of building the graph from the sets.
+type materialized[N, E any] struct {
+	graph    *graph.TOf[N, E]
+	dangling graph.EdgeSetOf[N, E]
+	// suppressed are the edges left out to keep the graph acyclic
+	suppressed graph.EdgeSetOf[N, E]
+	// tombstones are the edges the RemoveWins policy wants removed
+	tombstones []*graph.EdgeOf[N, E]
+}
+
+// materialize builds the graph from the operations that happened no later
+// than until, or from all of them when until is zero.
+func (s *ElementGraphOf[N, E]) materialize(until time.Time) materialized[N, E] {
+	m := materialized[N, E]{
+		graph:      s.newGraph(),
+		dangling:   make(graph.EdgeSetOf[N, E]),
+		suppressed: make(graph.EdgeSetOf[N, E]),
+		tombstones: make([]*graph.EdgeOf[N, E], 0),
+	}
+	g := m.graph
+
+	addSet, removeSet := setsAt(s.NodeSet, until)
+
+	for _, k := range ids(addSet, s.deterministic) {
+		v := addSet[k]
+		if !live(k, addSet, removeSet, until) {
+			continue
+		}
+
+		node := v.Payload
+		g.AddNode(graph.NewNodeOf[N, E](node.ID, node.Payload))
+	}
+
+	edgeAddSet, edgeRemoveSet := setsAt(s.EdgeSet, until)
+
+	for _, k := range s.edgeIDs(edgeAddSet) {
+		v := edgeAddSet[k]
+		if !live(k, edgeAddSet, edgeRemoveSet, until) {
+			continue
+		}
+
+		edge := v.Payload
+		if !g.NodeExists(edge.From) || !g.NodeExists(edge.To) {
+			switch s.resolveDangling(g, edge, removeSet, until) {
+			case AddWins:
+				for _, n := range []*graph.NodeOf[N, E]{edge.From, edge.To} {
+					if _, ok := removeSet[n.ID]; ok {
+						s.reviveNode(g, n)
+					}
+				}
+			case RemoveWins:
+				m.tombstones = append(m.tombstones, edge)
+				continue
+			default:
+				m.dangling[edge.ID] = edge
 				continue
 			}
 		}
 
-		node := v.Payload.(*graph.Node)
-		s.Graph.AddNode(graph.NewNode(node.ID, node.Payload))
+		if s.acyclic && s.closesCycle(g, edge) {
+			m.suppressed[edge.ID] = edge
+			continue
+		}
+
+		g.AddEdge(bind[N, E](g, edge))
 	}
 
-	addSet = s.EdgeSet.GetAddSet()
-	removeSet = s.EdgeSet.GetRemoveSet()
+	return m
+}
 
-	for k, v := range addSet {
-		removedEdge, ok := removeSet[k]
-		if ok {
-			if removedEdge.Timestamp.After(v.Timestamp) {
-				continue
+// bind returns a copy of the edge whose endpoints are the nodes of g, so the
+// edges of the graph never point at the nodes of the sets or of the caller.
+func bind[N, E any](g graph.GraphOf[N, E], edge *graph.EdgeOf[N, E]) *graph.EdgeOf[N, E] {
+	e := *edge
+	if from := g.GetNode(edge.From.ID); from != nil {
+		e.From = from
+	}
+	if to := g.GetNode(edge.To.ID); to != nil {
+		e.To = to
+	}
+	return &e
+}
+
+// resolveDangling decides how a live edge that could not be added to g is
+// handled. Endpoints that were never seen by this replica are not treated as
+// removed, the edge is kept dangling until they arrive, even when its other
+// endpoint was removed.
+func (s *ElementGraphOf[N, E]) resolveDangling(g graph.GraphOf[N, E], edge *graph.EdgeOf[N, E], removeSet twoPSet.SetOf[*graph.NodeOf[N, E]], until time.Time) EdgePolicy {
+	policy := KeepDangling
+	for _, n := range []*graph.NodeOf[N, E]{edge.From, edge.To} {
+		if g.NodeExists(n) {
+			continue
+		}
+
+		op, ok := removeSet[n.ID]
+		if !ok || !happened(op, until) {
+			return KeepDangling
+		}
+		policy = s.edgePolicy
+	}
+
+	return policy
+}
+
+func (s *ElementGraphOf[N, E]) reviveNode(g graph.GraphOf[N, E], node *graph.NodeOf[N, E]) {
+	if g.NodeExists(node) {
+		return
+	}
+
+	payload := node.Payload
+	if op, ok := s.NodeSet.GetAddSet()[node.ID]; ok {
+		payload = op.Payload.Payload
+	}
+	g.AddNode(graph.NewNodeOf[N, E](node.ID, payload))
+}
+
+func (s *ElementGraphOf[N, E]) newGraph() *graph.TOf[N, E] {
+	opts := make([]graph.Option, 0)
+	if s.deterministic {
+		opts = append(opts, graph.WithDeterministicOrder())
+	}
+	if s.undirected {
+		opts = append(opts, graph.WithUndirectedEdges())
+	}
+	if s.simple {
+		opts = append(opts, graph.WithSimpleGraph())
+	}
+	return graph.NewOf[N, E](opts...)
+}
+
+// ids returns the ids of the set, sorted when the order is deterministic.
+func ids[V any](set twoPSet.SetOf[V], deterministic bool) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(set))
+	for k := range set {
+		ids = append(ids, k)
+	}
+
+	if deterministic {
+		sort.Slice(ids, func(i, j int) bool {
+			return bytes.Compare(ids[i][:], ids[j][:]) < 0
+		})
+	}
+	return ids
+}
+
+// edgeIDs returns the ids of the edge set. When edges can be left out of the
+// graph, in acyclic mode or as parallel edges of a simple graph, the edges are
+// ordered by when they were added, so every replica keeps the same edges.
+func (s *ElementGraphOf[N, E]) edgeIDs(set twoPSet.SetOf[*graph.EdgeOf[N, E]]) []uuid.UUID {
+	ids := ids(set, s.deterministic)
+	if !s.acyclic && !s.simple {
+		return ids
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := set[ids[i]], set[ids[j]]
+		if a.Before(b) || b.Before(a) {
+			return a.Before(b)
+		}
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+	return ids
+}
+
+// closesCycle reports whether adding the edge to g would create a cycle. An
+// undirected edge can be traversed back to where it starts, so it is a cycle
+// on its own, as graph.HasCycle sees it.
+func (s *ElementGraphOf[N, E]) closesCycle(g graph.GraphOf[N, E], edge *graph.EdgeOf[N, E]) bool {
+	return edge.Undirected || s.undirected || graph.IsReachable(g, edge.To, edge.From)
+}
+
+// reachEdge adds an edge of the graph to the reachability index, both ways
+// when it is undirected.
+func (s *ElementGraphOf[N, E]) reachEdge(edge *graph.EdgeOf[N, E]) {
+	if s.reach == nil {
+		return
+	}
+
+	s.reach.AddEdge(edge.From.ID, edge.To.ID)
+	if edge.Undirected || s.undirected {
+		s.reach.AddEdge(edge.To.ID, edge.From.ID)
+	}
+}
+
+func (s *ElementGraphOf[N, E]) invalidateReachability() {
+	if s.reach != nil {
+		s.reach.Invalidate()
+	}
+}
+
+func (s *ElementGraphOf[N, E]) incidentEdges(node *graph.NodeOf[N, E]) []*graph.EdgeOf[N, E] {
+	edges := make([]*graph.EdgeOf[N, E], 0)
+
+	existing := s.Graph.GetNode(node.ID)
+	if existing == nil {
+		return edges
+	}
+
+	for _, edge := range existing.Edges {
+		edges = append(edges, edge)
+	}
+	for _, edge := range s.Graph.InEdges(existing) {
+		if edge.From.ID != node.ID {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// live reports whether the element was added and not removed afterwards,
+// ignoring the operations that happened after until.
+func live[V any](id uuid.UUID, addSet, removeSet twoPSet.SetOf[V], until time.Time) bool {
+	added, ok := addSet[id]
+	if !ok || !happened(added, until) {
+		return false
+	}
+
+	removed, ok := removeSet[id]
+	return !ok || !happened(removed, until) || !removed.Timestamp.After(added.Timestamp)
+}
+
+// setsAt returns the add and remove sets of the given set. When until is set
+// and the set keeps a history, they are rebuilt from the latest events that
+// happened no later than until.
+func setsAt[V any](set twoPSet.TwoPSetOf[V], until time.Time) (twoPSet.SetOf[V], twoPSet.SetOf[V]) {
+	history := set.GetHistory()
+	if until.IsZero() || history == nil {
+		return set.GetAddSet(), set.GetRemoveSet()
+	}
+
+	addSet := make(twoPSet.SetOf[V])
+	removeSet := make(twoPSet.SetOf[V])
+	for k, events := range history {
+		for _, e := range events {
+			if !happened(e.OPOf, until) {
+				break
+			}
+			if e.Kind == twoPSet.Removed {
+				removeSet[k] = e.OPOf
+			} else {
+				addSet[k] = e.OPOf
 			}
 		}
-		s.Graph.AddEdge(v.Payload.(*graph.Edge))
 	}
+
+	return addSet, removeSet
+}
+
+func happened[V any](op twoPSet.OPOf[V], until time.Time) bool {
+	return until.IsZero() || !op.Timestamp.After(until)
 }
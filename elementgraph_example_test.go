@@ -4,6 +4,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/tauki/crdt/graph"
 	"log"
+	"time"
 )
 
 func ExampleElementGraph_AddNode() {
@@ -73,3 +74,29 @@ func ExampleElementGraph_RegenerateGraph() {
 
 	log.Println(g.Graph.NodeExists(node))
 }
+
+func ExampleElementGraph_AsOf() {
+	g := NewElementGraph()
+	node := graph.NewNode(uuid.New(), []byte{})
+	g.AddNode(node)
+
+	t := time.Now()
+	time.Sleep(time.Millisecond)
+	g.RemoveNode(node)
+
+	log.Println(g.AsOf(t).NodeExists(node))
+	log.Println(g.Graph.NodeExists(node))
+}
+
+func ExampleElementGraph_Fork() {
+	g := NewElementGraph()
+	node := graph.NewNode(uuid.New(), []byte{})
+	g.AddNode(node)
+
+	branch := g.Fork()
+	branch.RemoveNode(node)
+	log.Println(g.Graph.NodeExists(node))
+
+	g.Merge(branch)
+	log.Println(g.Graph.NodeExists(node))
+}
@@ -115,7 +115,7 @@ func TestElementGraph_RemoveNode_WithEdges(t *testing.T) {
 
 func TestElementGraph_RemoveNode_RemoveFromNodeSetFail(t *testing.T) {
 	g := NewElementGraph()
-	mockSet := &twoPSet.MockTwoPSet{}
+	mockSet := &twoPSet.MockTwoPSetOf[*graph.Node]{}
 	g.NodeSet = mockSet
 
 	mockSet.On("Remove",
@@ -196,7 +196,7 @@ func TestElementGraph_RemoveEdge_Idempotency(t *testing.T) {
 
 func TestElementGraph_RemoveEdge_RemoveFromEdgeSetFail(t *testing.T) {
 	g := NewElementGraph()
-	mockSet := &twoPSet.MockTwoPSet{}
+	mockSet := &twoPSet.MockTwoPSetOf[*graph.Edge]{}
 	g.EdgeSet = mockSet
 
 	mockSet.On("Remove",
@@ -232,8 +232,8 @@ func TestElementGraph_Merge(t *testing.T) {
 	assert.True(t, g2.Graph.NodeExists(node1))
 	assert.True(t, g2.Graph.NodeExists(node2))
 	assert.True(t, g2.Graph.EdgeExists(edge))
-	assert.Equal(t, node1, g2.NodeSet.GetAddSet()[node1.ID].Payload)
-	assert.Equal(t, node2, g2.NodeSet.GetAddSet()[node2.ID].Payload)
+	assert.Equal(t, graph.NewNode(node1.ID, node1.Payload), g2.NodeSet.GetAddSet()[node1.ID].Payload)
+	assert.Equal(t, graph.NewNode(node2.ID, node2.Payload), g2.NodeSet.GetAddSet()[node2.ID].Payload)
 	assert.Equal(t, edge, g2.EdgeSet.GetAddSet()[edge.ID].Payload)
 }
 
@@ -258,7 +258,7 @@ func TestElementGraph_Merge_Conflict(t *testing.T) {
 	g1.Merge(g2)
 	assert.True(t, g1.Graph.NodeExists(node2))
 	assert.True(t, g1.Graph.EdgeExists(edge2))
-	assert.Equal(t, node2, g1.NodeSet.GetAddSet()[nodeID].Payload)
+	assert.Equal(t, graph.NewNode(nodeID, node2.Payload), g1.NodeSet.GetAddSet()[nodeID].Payload)
 	assert.Equal(t, edge2, g1.EdgeSet.GetAddSet()[edgeID].Payload)
 }
 
@@ -378,3 +378,966 @@ func TestElementGraph_RegenerateGraph_RemovedEdgeSync(t *testing.T) {
 	assert.True(t, g.Graph.NodeExists(node2))
 	assert.False(t, g.Graph.EdgeExists(edge))
 }
+
+func TestElementGraph_RemoveNode_TombstonesAttachedEdges(t *testing.T) {
+	g := NewElementGraph()
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g.AddNode(node1)
+	g.AddNode(node2)
+
+	edge1 := graph.NewEdge(uuid.New(), node1, node2)
+	edge2 := graph.NewEdge(uuid.New(), node2, node1)
+	g.AddEdge(edge1)
+	g.AddEdge(edge2)
+
+	time.Sleep(1)
+	g.RemoveNode(node2)
+
+	assert.Contains(t, g.EdgeSet.GetRemoveSet(), edge1.ID)
+	assert.Contains(t, g.EdgeSet.GetRemoveSet(), edge2.ID)
+	assert.Empty(t, g.DanglingEdges())
+}
+
+func TestElementGraph_EdgePolicy_ConcurrentEdgeAddAndEndpointRemove(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      EdgePolicy
+		nodeExists  bool
+		edgeExists  bool
+		dangling    int
+		edgeRemoved bool
+	}{
+		{name: "KeepDangling", policy: KeepDangling, nodeExists: false, edgeExists: false, dangling: 1, edgeRemoved: false},
+		{name: "AddWins", policy: AddWins, nodeExists: true, edgeExists: true, dangling: 0, edgeRemoved: false},
+		{name: "RemoveWins", policy: RemoveWins, nodeExists: false, edgeExists: false, dangling: 0, edgeRemoved: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g1 := NewElementGraph(WithEdgePolicy(tt.policy))
+			g2 := NewElementGraph(WithEdgePolicy(tt.policy))
+
+			node1 := graph.NewNode(uuid.New(), []byte("hello"))
+			node2 := graph.NewNode(uuid.New(), []byte("world"))
+			g1.AddNode(node1)
+			g1.AddNode(node2)
+			g2.Merge(g1)
+
+			edge := graph.NewEdge(uuid.New(), node1, node2)
+			g1.AddEdge(edge)
+			time.Sleep(1)
+			g2.RemoveNode(g2.Graph.GetNode(node2.ID))
+
+			g1.Merge(g2)
+			g2.Merge(g1)
+
+			for _, g := range []*ElementGraph{g1, g2} {
+				assert.Equal(t, tt.policy, g.EdgePolicy())
+				assert.True(t, g.Graph.NodeExists(node1))
+				assert.Equal(t, tt.nodeExists, g.Graph.NodeExists(node2))
+				assert.Equal(t, tt.edgeExists, g.Graph.EdgeExists(edge))
+				assert.Len(t, g.DanglingEdges(), tt.dangling)
+
+				_, removed := g.EdgeSet.GetRemoveSet()[edge.ID]
+				assert.Equal(t, tt.edgeRemoved, removed)
+			}
+		})
+	}
+}
+
+func TestElementGraph_EdgePolicy_UnknownEndpointKeptDangling(t *testing.T) {
+	for _, policy := range []EdgePolicy{KeepDangling, AddWins, RemoveWins} {
+		g := NewElementGraph(WithEdgePolicy(policy))
+
+		node1 := graph.NewNode(uuid.New(), []byte("hello"))
+		node2 := graph.NewNode(uuid.New(), []byte("world"))
+		g.AddNode(node1)
+
+		edge := graph.NewEdge(uuid.New(), node1, node2)
+		g.EdgeSet.Add(edge.ID, edge)
+		g.RegenerateGraph()
+
+		assert.False(t, g.Graph.NodeExists(node2))
+		assert.Equal(t, []*graph.Edge{edge}, g.DanglingEdges())
+		assert.NotContains(t, g.EdgeSet.GetRemoveSet(), edge.ID)
+	}
+}
+
+func TestElementGraph_RemoveEdge_Dangling(t *testing.T) {
+	g1 := NewElementGraph()
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g1.AddNode(node1)
+	g1.AddNode(node2)
+	g2 := NewElementGraph()
+	assert.NoError(t, g2.Merge(g1))
+
+	g1.RemoveNode(node2)
+	time.Sleep(1)
+	edge := graph.NewEdge(uuid.New(), g2.Graph.GetNode(node1.ID), g2.Graph.GetNode(node2.ID))
+	g2.AddEdge(edge)
+	assert.NoError(t, g1.Merge(g2))
+	assert.Len(t, g1.DanglingEdges(), 1)
+
+	g1.RemoveEdge(g1.DanglingEdges()[0])
+	assert.Empty(t, g1.DanglingEdges())
+	assert.Contains(t, g1.EdgeSet.GetRemoveSet(), edge.ID)
+	assert.Empty(t, g1.Check())
+
+	assert.NoError(t, g2.Merge(g1))
+	assert.False(t, g2.Graph.EdgeExists(edge))
+	assert.Equal(t, g1.Digest(), g2.Digest())
+}
+
+func TestElementGraph_EdgePolicy_AddWinsDoesNotReviveUnknownEndpoint(t *testing.T) {
+	g := NewElementGraph(WithEdgePolicy(AddWins))
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g.AddNode(node1)
+	time.Sleep(1)
+	g.RemoveNode(node1)
+
+	edge := graph.NewEdge(uuid.New(), node1, node2)
+	g.EdgeSet.Add(edge.ID, edge)
+	g.RegenerateGraph()
+
+	assert.False(t, g.Graph.NodeExists(node1))
+	assert.False(t, g.Graph.NodeExists(node2))
+	assert.Equal(t, []*graph.Edge{edge}, g.DanglingEdges())
+	assert.Empty(t, g.Check())
+
+	g.NodeSet.Add(node2.ID, node2)
+	g.RegenerateGraph()
+	assert.True(t, g.Graph.NodeExists(node1))
+	assert.True(t, g.Graph.EdgeExists(edge))
+	assert.Empty(t, g.Check())
+}
+
+func TestElementGraph_EdgePolicy_AddNodeResolvesDangling(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      EdgePolicy
+		edgeExists  bool
+		dangling    int
+		edgeRemoved bool
+	}{
+		{name: "KeepDangling", policy: KeepDangling, edgeExists: false, dangling: 1, edgeRemoved: false},
+		{name: "AddWins", policy: AddWins, edgeExists: true, dangling: 0, edgeRemoved: false},
+		{name: "RemoveWins", policy: RemoveWins, edgeExists: false, dangling: 0, edgeRemoved: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewElementGraph(WithEdgePolicy(tt.policy))
+
+			node1 := graph.NewNode(uuid.New(), []byte("hello"))
+			node2 := graph.NewNode(uuid.New(), []byte("world"))
+			node3 := graph.NewNode(uuid.New(), []byte("!"))
+			g.AddNode(node1)
+			g.AddNode(node3)
+			time.Sleep(1)
+			g.RemoveNode(node3)
+
+			edge12 := graph.NewEdge(uuid.New(), node1, node2)
+			edge32 := graph.NewEdge(uuid.New(), node3, node2)
+			g.EdgeSet.Add(edge12.ID, edge12)
+			g.EdgeSet.Add(edge32.ID, edge32)
+			g.RegenerateGraph()
+			assert.Len(t, g.DanglingEdges(), 2)
+
+			g.AddNode(node2)
+			assert.True(t, g.Graph.EdgeExists(edge12))
+			assert.Equal(t, tt.edgeExists, g.Graph.EdgeExists(edge32))
+			assert.Len(t, g.DanglingEdges(), tt.dangling)
+
+			_, removed := g.EdgeSet.GetRemoveSet()[edge32.ID]
+			assert.Equal(t, tt.edgeRemoved, removed)
+			assert.Empty(t, g.Check())
+		})
+	}
+}
+
+func TestElementGraph_EdgePolicy_AddWinsKeepsLocalRemove(t *testing.T) {
+	g1 := NewElementGraph(WithEdgePolicy(AddWins))
+	g2 := NewElementGraph(WithEdgePolicy(AddWins))
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	edge := graph.NewEdge(uuid.New(), node1, node2)
+	g1.AddNode(node1)
+	g1.AddNode(node2)
+	g1.AddEdge(edge)
+
+	time.Sleep(1)
+	g1.RemoveNode(node2)
+	g2.Merge(g1)
+
+	assert.False(t, g2.Graph.NodeExists(node2))
+	assert.False(t, g2.Graph.EdgeExists(edge))
+	assert.Empty(t, g2.DanglingEdges())
+}
+
+func TestElementGraph_AsOf(t *testing.T) {
+	g := NewElementGraph()
+
+	before := time.Now()
+	time.Sleep(time.Millisecond)
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g.AddNode(node1)
+	g.AddNode(node2)
+
+	time.Sleep(time.Millisecond)
+	nodesAdded := time.Now()
+	time.Sleep(time.Millisecond)
+
+	edge := graph.NewEdge(uuid.New(), node1, node2)
+	g.AddEdge(edge)
+
+	time.Sleep(time.Millisecond)
+	edgeAdded := time.Now()
+	time.Sleep(time.Millisecond)
+
+	g.RemoveNode(node2)
+
+	snapshot := g.AsOf(before)
+	assert.Empty(t, snapshot.List)
+
+	snapshot = g.AsOf(nodesAdded)
+	assert.True(t, snapshot.NodeExists(node1))
+	assert.True(t, snapshot.NodeExists(node2))
+	assert.False(t, snapshot.EdgeExists(edge))
+
+	snapshot = g.AsOf(edgeAdded)
+	assert.True(t, snapshot.NodeExists(node2))
+	assert.True(t, snapshot.EdgeExists(edge))
+	assert.Equal(t, []byte("world"), snapshot.GetNode(node2.ID).Payload)
+
+	snapshot = g.AsOf(time.Now())
+	assert.True(t, snapshot.NodeExists(node1))
+	assert.False(t, snapshot.NodeExists(node2))
+	assert.False(t, snapshot.EdgeExists(edge))
+}
+
+func TestElementGraph_AsOf_IsACopy(t *testing.T) {
+	g := NewElementGraph()
+
+	node := graph.NewNode(uuid.New(), []byte("hello"))
+	g.AddNode(node)
+
+	snapshot := g.AsOf(time.Now())
+	snapshot.RemoveNode(node)
+
+	assert.True(t, g.Graph.NodeExists(node))
+	assert.Contains(t, g.AsOf(time.Now()).List, node.ID)
+}
+
+func TestElementGraph_AsOf_RemoveWinsDoesNotTombstone(t *testing.T) {
+	g := NewElementGraph(WithEdgePolicy(RemoveWins))
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g.AddNode(node1)
+	g.AddNode(node2)
+
+	time.Sleep(time.Millisecond)
+	_ = g.NodeSet.Remove(node2.ID)
+	edge := graph.NewEdge(uuid.New(), node1, node2)
+	g.EdgeSet.Add(edge.ID, edge)
+
+	snapshot := g.AsOf(time.Now())
+	assert.False(t, snapshot.EdgeExists(edge))
+	assert.NotContains(t, g.EdgeSet.GetRemoveSet(), edge.ID)
+}
+
+func TestElementGraph_ReplicaID(t *testing.T) {
+	replica := uuid.New()
+	g := NewElementGraph(WithReplicaID(replica))
+	assert.Equal(t, replica, g.ReplicaID())
+
+	node := graph.NewNode(uuid.New(), []byte("hello"))
+	g.AddNode(node)
+	assert.Equal(t, replica, g.NodeSet.GetAddSet()[node.ID].Replica)
+
+	assert.NotEqual(t, NewElementGraph().ReplicaID(), NewElementGraph().ReplicaID())
+}
+
+func TestElementGraph_UpdateNode(t *testing.T) {
+	g := NewElementGraph()
+
+	node := graph.NewNode(uuid.New(), []byte("hello"))
+	assert.False(t, g.UpdateNode(node))
+
+	g.AddNode(node)
+	time.Sleep(1)
+	assert.True(t, g.UpdateNode(graph.NewNode(node.ID, []byte("world"))))
+	assert.Equal(t, []byte("world"), g.Graph.GetNode(node.ID).Payload)
+
+	g.RegenerateGraph()
+	assert.Equal(t, []byte("world"), g.Graph.GetNode(node.ID).Payload)
+}
+
+func TestElementGraph_UpdateNode_Isolation(t *testing.T) {
+	g := NewElementGraph()
+	node1 := graph.NewNode(uuid.New(), []byte("v1"))
+	node2 := graph.NewNode(uuid.New(), []byte("other"))
+	g.AddNode(node1)
+	g.AddNode(node2)
+	edge := graph.NewEdge(uuid.New(), node1, node2)
+	g.AddEdge(edge)
+
+	fork := g.Fork()
+	merged := NewElementGraph()
+	assert.NoError(t, merged.Merge(g))
+	added := g.NodeSet.GetAddSet()[node1.ID]
+
+	time.Sleep(1)
+	assert.True(t, g.UpdateNode(graph.NewNode(node1.ID, []byte("v2"))))
+
+	// the node of the caller and the recorded operation are left unchanged
+	assert.Equal(t, []byte("v1"), node1.Payload)
+	assert.Equal(t, []byte("v1"), added.Payload.Payload)
+	for _, other := range []*ElementGraph{fork, merged} {
+		assert.Equal(t, []byte("v1"), other.NodeSet.GetAddSet()[node1.ID].Payload.Payload)
+		assert.Equal(t, []byte("v1"), other.Graph.GetNode(node1.ID).Payload)
+		assert.Empty(t, other.Check())
+	}
+
+	// the edges of the node are moved to the new node of the graph
+	updated := g.Graph.GetNode(node1.ID)
+	assert.NotSame(t, node1, updated)
+	assert.Len(t, updated.Edges, 1)
+	assert.Same(t, updated, updated.Edges[edge.ID].From)
+	assert.Empty(t, g.Check())
+
+	assert.NoError(t, fork.Merge(g))
+	assert.Equal(t, []byte("v2"), fork.Graph.GetNode(node1.ID).Payload)
+	assert.Empty(t, fork.Check())
+}
+
+func TestElementGraph_History(t *testing.T) {
+	replica1 := uuid.New()
+	replica2 := uuid.New()
+	g1 := NewElementGraph(WithReplicaID(replica1), WithHistory())
+	g2 := NewElementGraph(WithReplicaID(replica2), WithHistory())
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	edge := graph.NewEdge(uuid.New(), node1, node2)
+	g1.AddNode(node1)
+	g1.AddNode(node2)
+	g1.AddEdge(edge)
+
+	g2.Merge(g1)
+	time.Sleep(1)
+	g2.UpdateNode(graph.NewNode(node1.ID, []byte("hi")))
+	time.Sleep(1)
+	g2.RemoveEdge(g2.Graph.GetNode(node1.ID).Edges[edge.ID])
+	g1.Merge(g2)
+
+	events := g1.History(edge.ID)
+	assert.Len(t, events, 2)
+	assert.Equal(t, twoPSet.Added, events[0].Kind)
+	assert.Equal(t, replica1, events[0].Replica)
+	assert.Equal(t, twoPSet.Removed, events[1].Kind)
+	assert.Equal(t, replica2, events[1].Replica)
+
+	events = g1.History(node1.ID)
+	assert.Len(t, events, 2)
+	assert.Equal(t, twoPSet.Updated, events[1].Kind)
+	assert.Equal(t, replica2, events[1].Replica)
+	assert.Equal(t, []byte("hi"), events[1].Payload.(*graph.Node).Payload)
+}
+
+func TestElementGraph_History_Disabled(t *testing.T) {
+	g := NewElementGraph()
+
+	node := graph.NewNode(uuid.New(), []byte("hello"))
+	g.AddNode(node)
+
+	assert.Empty(t, g.History(node.ID))
+}
+
+func TestElementGraph_AsOf_WithHistory(t *testing.T) {
+	g := NewElementGraph(WithHistory())
+
+	node := graph.NewNode(uuid.New(), []byte("hello"))
+	g.AddNode(node)
+
+	time.Sleep(time.Millisecond)
+	added := time.Now()
+	time.Sleep(time.Millisecond)
+
+	g.RemoveNode(node)
+	time.Sleep(time.Millisecond)
+	removed := time.Now()
+	time.Sleep(time.Millisecond)
+
+	g.AddNode(graph.NewNode(node.ID, []byte("world")))
+
+	assert.True(t, g.AsOf(added).NodeExists(node))
+	assert.Equal(t, []byte("hello"), g.AsOf(added).GetNode(node.ID).Payload)
+	assert.False(t, g.AsOf(removed).NodeExists(node))
+	assert.Equal(t, []byte("world"), g.AsOf(time.Now()).GetNode(node.ID).Payload)
+}
+
+func TestElementGraph_AsOf_UpdateNode(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for _, history := range []bool{false, true} {
+		opts := []Option{WithClock(clock)}
+		if history {
+			opts = append(opts, WithHistory())
+		}
+		g := NewElementGraph(opts...)
+
+		node := graph.NewNode(uuid.New(), []byte("hello"))
+		g.AddNode(node)
+		mid := clock()
+		g.UpdateNode(graph.NewNode(node.ID, []byte("world")))
+
+		// without history the update replaced the only add of the node, so
+		// the node is missing from the past
+		past := g.AsOf(mid)
+		assert.Equal(t, history, past.NodeExists(node), history)
+		if history {
+			assert.Equal(t, []byte("hello"), past.GetNode(node.ID).Payload)
+		}
+		assert.Equal(t, []byte("world"), g.AsOf(clock()).GetNode(node.ID).Payload)
+	}
+}
+
+func TestElementGraph_Fork(t *testing.T) {
+	g := NewElementGraph(WithEdgePolicy(AddWins))
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g.AddNode(node1)
+	g.AddNode(node2)
+
+	fork := g.Fork()
+	assert.NotEqual(t, g.ReplicaID(), fork.ReplicaID())
+	assert.Equal(t, AddWins, fork.EdgePolicy())
+	assert.True(t, fork.Graph.NodeExists(node1))
+	assert.True(t, fork.Graph.NodeExists(node2))
+	assert.NotSame(t, g.Graph.GetNode(node1.ID), fork.Graph.GetNode(node1.ID))
+	assert.False(t, fork.CanUndo())
+	assert.Empty(t, fork.Check())
+
+	edge := graph.NewEdge(uuid.New(), node1, node2)
+	fork.AddEdge(edge)
+	time.Sleep(1)
+	fork.RemoveNode(node1)
+
+	assert.True(t, g.Graph.NodeExists(node1))
+	assert.False(t, g.Graph.EdgeExists(edge))
+	assert.NotContains(t, g.EdgeSet.GetAddSet(), edge.ID)
+	assert.NotContains(t, g.NodeSet.GetRemoveSet(), node1.ID)
+
+	g.Merge(fork)
+	assert.False(t, g.Graph.NodeExists(node1))
+	assert.True(t, g.Graph.NodeExists(node2))
+	assert.Contains(t, g.EdgeSet.GetRemoveSet(), edge.ID)
+}
+
+func TestElementGraph_Fork_ConcurrentOperations(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+	g := NewElementGraph(WithClock(clock))
+
+	node := graph.NewNode(uuid.New(), []byte("hello"))
+	g.AddNode(node)
+
+	// operations at the same time are ordered by replica id, which differs
+	// between the fork and g
+	fork := g.Fork()
+	g.UpdateNode(graph.NewNode(node.ID, []byte("g")))
+	fork.UpdateNode(graph.NewNode(node.ID, []byte("fork")))
+	assert.NotEqual(t, g.NodeSet.GetAddSet()[node.ID].Replica, fork.NodeSet.GetAddSet()[node.ID].Replica)
+
+	assert.NoError(t, g.Merge(fork))
+	assert.NoError(t, fork.Merge(g))
+	assert.Equal(t, g.Digest(), fork.Digest())
+	assert.Equal(t, g.GraphDigest(), fork.GraphDigest())
+}
+
+func TestElementGraph_Fork_CopiesGraph(t *testing.T) {
+	g := NewElementGraph(WithAcyclic(), WithReachabilityIndex())
+
+	node1 := graph.NewNode(uuid.New(), []byte("a"))
+	node2 := graph.NewNode(uuid.New(), []byte("b"))
+	g.AddNode(node1)
+	g.AddNode(node2)
+
+	other := g.Fork()
+	g.AddEdge(graph.NewEdge(uuid.New(), node1, node2))
+	time.Sleep(1)
+	other.AddEdge(graph.NewEdge(uuid.New(), node2, node1))
+	assert.NoError(t, g.Merge(other))
+	assert.Len(t, g.SuppressedEdges(), 1)
+
+	fork := g.Fork()
+	assert.Len(t, fork.SuppressedEdges(), 1)
+	assert.Len(t, fork.Reachability().Descendants(fork.Graph.GetNode(node1.ID)), 1)
+	assert.Empty(t, fork.Check())
+
+	fork.RemoveNode(node2)
+	assert.Empty(t, fork.SuppressedEdges())
+	assert.Len(t, g.SuppressedEdges(), 1)
+	assert.True(t, g.Graph.NodeExists(node2))
+	assert.Len(t, g.Graph.Edges(), 1)
+	assert.Empty(t, g.Check())
+}
+
+func TestElementGraph_Fork_Discard(t *testing.T) {
+	g := NewElementGraph()
+
+	node := graph.NewNode(uuid.New(), []byte("hello"))
+	g.AddNode(node)
+
+	fork := g.Fork()
+	fork.AddNode(graph.NewNode(uuid.New(), []byte("world")))
+	assert.Len(t, fork.NodeSet.GetAddSet(), 2)
+
+	g.AddNode(graph.NewNode(uuid.New(), []byte("!")))
+	assert.Len(t, g.NodeSet.GetAddSet(), 2)
+}
+
+func TestElementGraph_WithDeterministicOrder(t *testing.T) {
+	g := NewElementGraph(WithDeterministicOrder())
+	assert.True(t, g.Graph.(*graph.T).Deterministic())
+
+	node := graph.NewNode(uuid.New(), []byte("hello"))
+	g.AddNode(node)
+	for i := 0; i < 10; i++ {
+		n := graph.NewNode(uuid.New(), []byte{})
+		g.AddNode(n)
+		g.AddEdge(graph.NewEdge(uuid.New(), node, n))
+	}
+
+	g.RegenerateGraph()
+	assert.True(t, g.Graph.(*graph.T).Deterministic())
+	assert.Equal(t, graph.SortNodes(g.Graph.Nodes()), g.Graph.Nodes())
+	assert.True(t, g.AsOf(time.Now()).Deterministic())
+	assert.True(t, g.Fork().Graph.(*graph.T).Deterministic())
+
+	for i := 0; i < 10; i++ {
+		edge := graph.NewEdge(uuid.New(), node, graph.NewNode(uuid.New(), nil))
+		g.EdgeSet.Add(edge.ID, edge)
+	}
+	g.RegenerateGraph()
+
+	dangling := g.DanglingEdges()
+	assert.Len(t, dangling, 10)
+	assert.Equal(t, graph.SortEdges(g.DanglingEdges()), dangling)
+}
+
+func TestElementGraph_Merge_CycleDetection(t *testing.T) {
+	g1 := NewElementGraph()
+	g2 := NewElementGraph()
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g1.AddNode(node1)
+	g1.AddNode(node2)
+	g2.Merge(g1)
+
+	g1.AddEdge(graph.NewEdge(uuid.New(), node1, node2))
+	g2.AddEdge(graph.NewEdge(uuid.New(), g2.Graph.GetNode(node2.ID), g2.Graph.GetNode(node1.ID)))
+	assert.False(t, graph.HasCycle(g1.Graph))
+	assert.False(t, graph.HasCycle(g2.Graph))
+
+	g1.Merge(g2)
+	assert.True(t, graph.HasCycle(g1.Graph))
+
+	_, err := graph.TopologicalSort(g1.Graph)
+	assert.Error(t, err)
+}
+
+func TestElementGraph_WithAcyclic_AddEdge(t *testing.T) {
+	g := NewElementGraph(WithAcyclic())
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g.AddNode(node1)
+	g.AddNode(node2)
+
+	edge12 := graph.NewEdge(uuid.New(), node1, node2)
+	edge21 := graph.NewEdge(uuid.New(), node2, node1)
+	edge11 := graph.NewEdge(uuid.New(), node1, node1)
+	g.AddEdge(edge12)
+	g.AddEdge(edge21)
+	g.AddEdge(edge11)
+
+	assert.True(t, g.Graph.EdgeExists(edge12))
+	assert.False(t, g.Graph.EdgeExists(edge21))
+	assert.False(t, g.Graph.EdgeExists(edge11))
+	assert.NotContains(t, g.EdgeSet.GetAddSet(), edge21.ID)
+	assert.NotContains(t, g.EdgeSet.GetAddSet(), edge11.ID)
+}
+
+func TestElementGraph_WithAcyclic_UndirectedEdge(t *testing.T) {
+	g := NewElementGraph(WithAcyclic())
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g.AddNode(node1)
+	g.AddNode(node2)
+
+	undirected := graph.NewUndirectedEdge(uuid.New(), node1, node2)
+	assert.False(t, g.AddEdge(undirected))
+	assert.NotContains(t, g.EdgeSet.GetAddSet(), undirected.ID)
+
+	// merged from a replica without WithAcyclic, the edge is suppressed
+	other := NewElementGraph()
+	assert.NoError(t, other.Merge(g))
+	other.AddEdge(graph.NewUndirectedEdge(undirected.ID, node1, node2))
+	assert.NoError(t, g.Merge(other))
+
+	assert.Empty(t, g.Graph.Edges())
+	assert.Len(t, g.SuppressedEdges(), 1)
+	assert.False(t, graph.HasCycle[[]byte, []byte](g.Graph))
+	_, err := graph.TopologicalSort[[]byte, []byte](g.Graph)
+	assert.NoError(t, err)
+	assert.Empty(t, g.Check())
+}
+
+func TestElementGraph_WithAcyclic_Merge(t *testing.T) {
+	g1 := NewElementGraph(WithAcyclic())
+	g2 := NewElementGraph(WithAcyclic())
+	g3 := NewElementGraph(WithAcyclic())
+
+	nodes := make([]*graph.Node, 3)
+	for i := range nodes {
+		nodes[i] = graph.NewNode(uuid.New(), []byte{})
+		g1.AddNode(nodes[i])
+	}
+	g2.Merge(g1)
+	g3.Merge(g1)
+
+	edge01 := graph.NewEdge(uuid.New(), nodes[0], nodes[1])
+	g1.AddEdge(edge01)
+	time.Sleep(1)
+	edge12 := graph.NewEdge(uuid.New(), g2.Graph.GetNode(nodes[1].ID), g2.Graph.GetNode(nodes[2].ID))
+	g2.AddEdge(edge12)
+	time.Sleep(1)
+	edge20 := graph.NewEdge(uuid.New(), g3.Graph.GetNode(nodes[2].ID), g3.Graph.GetNode(nodes[0].ID))
+	g3.AddEdge(edge20)
+
+	g1.Merge(g3)
+	g1.Merge(g2)
+	g2.Merge(g1)
+	g3.Merge(g2)
+
+	for _, g := range []*ElementGraph{g1, g2, g3} {
+		assert.False(t, graph.HasCycle(g.Graph))
+		assert.True(t, g.Graph.EdgeExists(edge01))
+		assert.True(t, g.Graph.EdgeExists(edge12))
+		assert.False(t, g.Graph.EdgeExists(edge20))
+		assert.Equal(t, []*graph.Edge{edge20}, g.SuppressedEdges())
+		assert.Contains(t, g.EdgeSet.GetAddSet(), edge20.ID)
+	}
+
+	time.Sleep(1)
+	g1.RemoveEdge(edge01)
+	assert.True(t, g1.Graph.EdgeExists(edge20))
+	assert.Empty(t, g1.SuppressedEdges())
+	assert.Empty(t, g1.Check())
+
+	time.Sleep(1)
+	g2.RemoveNode(g2.Graph.GetNode(nodes[1].ID))
+	assert.True(t, g2.Graph.EdgeExists(edge20))
+	assert.Empty(t, g2.SuppressedEdges())
+	assert.Empty(t, g2.Check())
+}
+
+func TestElementGraph_WithAcyclic_RemoveEdgeReleasesSuppressed(t *testing.T) {
+	g1 := NewElementGraph(WithAcyclic())
+	g2 := NewElementGraph(WithAcyclic())
+
+	x := graph.NewNode(uuid.New(), []byte("x"))
+	y := graph.NewNode(uuid.New(), []byte("y"))
+	g1.AddNode(x)
+	g1.AddNode(y)
+	g2.Merge(g1)
+
+	xy := graph.NewEdge(uuid.New(), x, y)
+	g1.AddEdge(xy)
+	time.Sleep(1)
+	yx := graph.NewEdge(uuid.New(), g2.Graph.GetNode(y.ID), g2.Graph.GetNode(x.ID))
+	g2.AddEdge(yx)
+
+	assert.NoError(t, g1.Merge(g2))
+	assert.Equal(t, []*graph.Edge{yx}, g1.SuppressedEdges())
+
+	time.Sleep(1)
+	g1.RemoveEdge(g1.Graph.GetNode(x.ID).Edges[xy.ID])
+	assert.True(t, g1.Graph.EdgeExists(yx))
+	assert.Empty(t, g1.SuppressedEdges())
+	assert.Empty(t, g1.Check())
+}
+
+func TestElementGraph_WithoutAcyclic_MergeKeepsCycle(t *testing.T) {
+	g := NewElementGraph()
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g.AddNode(node1)
+	g.AddNode(node2)
+	g.AddEdge(graph.NewEdge(uuid.New(), node1, node2))
+	g.AddEdge(graph.NewEdge(uuid.New(), node2, node1))
+
+	g.RegenerateGraph()
+	assert.True(t, graph.HasCycle(g.Graph))
+	assert.Empty(t, g.SuppressedEdges())
+}
+
+func TestElementGraph_WithReachabilityIndex(t *testing.T) {
+	g1 := NewElementGraph(WithReachabilityIndex())
+	g2 := NewElementGraph(WithReachabilityIndex())
+	assert.Nil(t, NewElementGraph().Reachability())
+
+	nodes := make([]*graph.Node, 3)
+	for i := range nodes {
+		nodes[i] = graph.NewNode(uuid.New(), []byte{})
+		g1.AddNode(nodes[i])
+	}
+	g1.AddEdge(graph.NewEdge(uuid.New(), nodes[0], nodes[1]))
+
+	r := g1.Reachability()
+	assert.True(t, r.IsReachable(nodes[0], nodes[1]))
+	assert.False(t, r.IsReachable(nodes[0], nodes[2]))
+
+	edge12 := graph.NewEdge(uuid.New(), nodes[1], nodes[2])
+	g1.AddEdge(edge12)
+	assert.True(t, r.IsReachable(nodes[0], nodes[2]))
+
+	time.Sleep(1)
+	g1.RemoveEdge(edge12)
+	assert.False(t, r.IsReachable(nodes[0], nodes[2]))
+
+	g2.Merge(g1)
+	g2.AddEdge(graph.NewEdge(uuid.New(), g2.Graph.GetNode(nodes[1].ID), g2.Graph.GetNode(nodes[2].ID)))
+	g1.Merge(g2)
+	assert.True(t, g1.Reachability().IsReachable(g1.Graph.GetNode(nodes[0].ID), g1.Graph.GetNode(nodes[2].ID)))
+
+	time.Sleep(1)
+	g1.RemoveNode(g1.Graph.GetNode(nodes[1].ID))
+	assert.False(t, g1.Reachability().IsReachable(g1.Graph.GetNode(nodes[0].ID), nodes[2]))
+
+	assert.NotNil(t, g1.Fork().Reachability())
+}
+
+func TestElementGraph_WithReachabilityIndex_Undirected(t *testing.T) {
+	for _, opts := range [][]Option{{}, {WithUndirectedEdges()}} {
+		g := NewElementGraph(append(opts, WithReachabilityIndex())...)
+
+		a := graph.NewNode(uuid.New(), []byte("a"))
+		b := graph.NewNode(uuid.New(), []byte("b"))
+		c := graph.NewNode(uuid.New(), []byte("c"))
+		g.AddNode(a)
+		g.AddNode(b)
+		g.AddNode(c)
+
+		r := g.Reachability()
+		for _, n := range []*graph.Node{a, b, c} {
+			assert.Empty(t, r.Descendants(n))
+			assert.Empty(t, r.Ancestors(n))
+		}
+
+		var ab *graph.Edge
+		if len(opts) == 0 {
+			ab = graph.NewUndirectedEdge(uuid.New(), a, b)
+		} else {
+			ab = graph.NewEdge(uuid.New(), a, b)
+		}
+		g.AddEdge(ab)
+		g.AddEdge(graph.NewUndirectedEdge(uuid.New(), b, c))
+
+		for _, pair := range [][2]*graph.Node{{a, b}, {b, a}, {c, a}, {a, c}} {
+			assert.Equal(t, graph.IsReachable(g.Graph, pair[0], pair[1]), r.IsReachable(pair[0], pair[1]))
+			assert.True(t, r.IsReachable(pair[0], pair[1]))
+		}
+	}
+}
+
+func TestElementGraph_UndirectedEdge(t *testing.T) {
+	g1 := NewElementGraph()
+	g2 := NewElementGraph()
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g1.AddNode(node1)
+	g1.AddNode(node2)
+
+	edge := graph.NewUndirectedEdge(uuid.New(), node1, node2)
+	g1.AddEdge(edge)
+	assert.Len(t, g1.EdgeSet.GetAddSet(), 1)
+
+	g2.Merge(g1)
+	assert.Equal(t, []*graph.Node{g2.Graph.GetNode(node1.ID)}, g2.Graph.OutNeighbors(node2.ID))
+	assert.Len(t, g2.Graph.FindPath(node2, node1), 2)
+
+	time.Sleep(1)
+	g2.RemoveEdge(edge)
+	g1.Merge(g2)
+	assert.Empty(t, g1.Graph.OutNeighbors(node2.ID))
+	assert.Empty(t, g1.Graph.OutNeighbors(node1.ID))
+}
+
+func TestElementGraph_WithUndirectedEdges(t *testing.T) {
+	g := NewElementGraph(WithUndirectedEdges())
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g.AddNode(node1)
+	g.AddNode(node2)
+	g.AddEdge(graph.NewEdge(uuid.New(), node1, node2))
+
+	assert.Len(t, g.Graph.OutNeighbors(node2.ID), 1)
+	g.RegenerateGraph()
+	assert.Len(t, g.Graph.OutNeighbors(node2.ID), 1)
+	assert.Len(t, g.Fork().Graph.OutNeighbors(node2.ID), 1)
+}
+
+func TestElementGraph_WithSimpleGraph(t *testing.T) {
+	g1 := NewElementGraph(WithSimpleGraph())
+	g2 := NewElementGraph(WithSimpleGraph())
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g1.AddNode(node1)
+	g1.AddNode(node2)
+	g2.Merge(g1)
+
+	edge1 := graph.NewEdge(uuid.New(), node1, node2)
+	edge1.Label = "likes"
+	g1.AddEdge(edge1)
+	edge2 := graph.NewEdge(uuid.New(), g2.Graph.GetNode(node1.ID), g2.Graph.GetNode(node2.ID))
+	edge2.Label = "likes"
+	time.Sleep(1)
+	g2.AddEdge(edge2)
+
+	assert.Equal(t, graph.EdgeID(node1.ID, node2.ID, "likes"), edge1.ID)
+	assert.Equal(t, edge1.ID, edge2.ID)
+
+	g1.Merge(g2)
+	g2.Merge(g1)
+	for _, g := range []*ElementGraph{g1, g2} {
+		assert.Len(t, g.EdgeSet.GetAddSet(), 1)
+		assert.Len(t, g.Graph.Edges(), 1)
+	}
+
+	time.Sleep(1)
+	g1.RemoveEdge(edge1)
+	g2.Merge(g1)
+	assert.Empty(t, g2.Graph.Edges())
+}
+
+func TestElementGraph_WithSimpleGraph_Undirected(t *testing.T) {
+	g := NewElementGraph(WithSimpleGraph(), WithUndirectedEdges())
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g.AddNode(node1)
+	g.AddNode(node2)
+
+	g.AddEdge(graph.NewEdge(uuid.New(), node1, node2))
+	g.AddEdge(graph.NewEdge(uuid.New(), node2, node1))
+	assert.Len(t, g.EdgeSet.GetAddSet(), 1)
+}
+
+func TestElementGraph_Multigraph(t *testing.T) {
+	g1 := NewElementGraph()
+	g2 := NewElementGraph()
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g1.AddNode(node1)
+	g1.AddNode(node2)
+	g2.Merge(g1)
+
+	g1.AddEdge(graph.NewEdge(uuid.New(), node1, node2))
+	g2.AddEdge(graph.NewEdge(uuid.New(), g2.Graph.GetNode(node1.ID), g2.Graph.GetNode(node2.ID)))
+
+	g1.Merge(g2)
+	assert.Len(t, g1.Graph.Edges(), 2)
+}
+
+func TestElementGraph_KeyedImportIsIdempotent(t *testing.T) {
+	users := graph.Namespace("users")
+	source := []struct{ from, to string }{
+		{"alice", "bob"},
+		{"bob", "carol"},
+		{"alice", "carol"},
+	}
+
+	load := func(g *ElementGraph) {
+		for _, row := range source {
+			from := graph.NewKeyedNode(users, row.from, []byte(row.from))
+			to := graph.NewKeyedNode(users, row.to, []byte(row.to))
+			g.AddNode(from)
+			g.AddNode(to)
+			g.AddEdge(graph.NewKeyedEdge(g.Graph.GetNode(from.ID), g.Graph.GetNode(to.ID), "follows"))
+		}
+	}
+
+	g1 := NewElementGraph()
+	g2 := NewElementGraph()
+	load(g1)
+	load(g1)
+	time.Sleep(1)
+	load(g2)
+
+	g1.Merge(g2)
+	g2.Merge(g1)
+	for _, g := range []*ElementGraph{g1, g2} {
+		assert.Len(t, g.NodeSet.GetAddSet(), 3)
+		assert.Len(t, g.EdgeSet.GetAddSet(), 3)
+		assert.Equal(t, 3, g.Graph.Len())
+		assert.Len(t, g.Graph.Edges(), 3)
+	}
+}
+
+func TestElementGraphOf_TypedPayloads(t *testing.T) {
+	type task struct {
+		Name string
+	}
+	type dependency struct {
+		Weight int
+	}
+
+	g1 := NewElementGraphOf[task, dependency](WithHistory())
+	g2 := NewElementGraphOf[task, dependency]()
+
+	build := graph.NewNodeOf[task, dependency](uuid.New(), task{Name: "build"})
+	deploy := graph.NewNodeOf[task, dependency](uuid.New(), task{Name: "deploy"})
+	g1.AddNode(build)
+	g1.AddNode(deploy)
+
+	edge := graph.NewEdgeOf(uuid.New(), build, deploy)
+	edge.Payload = dependency{Weight: 2}
+	g1.AddEdge(edge)
+
+	g2.Merge(g1)
+	assert.Equal(t, task{Name: "deploy"}, g2.Graph.GetNode(deploy.ID).Payload)
+	assert.Equal(t, dependency{Weight: 2}, g2.EdgeSet.GetAddSet()[edge.ID].Payload.Payload)
+
+	order, err := graph.TopologicalSort(g2.Graph)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"build", "deploy"}, []string{order[0].Payload.Name, order[1].Payload.Name})
+
+	g1.UpdateNode(graph.NewNodeOf[task, dependency](build.ID, task{Name: "compile"}))
+	events := g1.NodeHistory(build.ID)
+	assert.Len(t, events, 2)
+	assert.Equal(t, "compile", events[1].Payload.Payload.Name)
+	assert.Equal(t, twoPSet.Updated, events[1].Kind)
+}
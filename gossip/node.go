@@ -0,0 +1,404 @@
+// Package gossip keeps a cluster of ElementGraph replicas in sync. Every Node
+// periodically picks a few of its peers at random and exchanges with each of
+// them the operations where their states differ, so changes spread through
+// the cluster without every replica talking to every other one.
+package gossip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/tauki/crdt"
+	"github.com/tauki/crdt/replication"
+	"github.com/tauki/crdt/twoPSet"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type config struct {
+	lock     sync.Locker
+	interval time.Duration
+	fanout   int
+	seed     int64
+	now      func() time.Time
+}
+
+type Option func(*config)
+
+// WithLock sets the lock held while the ElementGraph is read or changed. The
+// application must hold the same lock when it uses the ElementGraph. A lock
+// private to the Node is used otherwise.
+func WithLock(lock sync.Locker) Option {
+	return func(c *config) {
+		c.lock = lock
+	}
+}
+
+// WithInterval sets how often Run starts a round, every second by default.
+// Intervals that are not positive are ignored.
+func WithInterval(interval time.Duration) Option {
+	return func(c *config) {
+		if interval > 0 {
+			c.interval = interval
+		}
+	}
+}
+
+// WithFanout sets how many peers are picked every round, 2 by default.
+// Fanouts that are not positive are ignored.
+func WithFanout(fanout int) Option {
+	return func(c *config) {
+		if fanout > 0 {
+			c.fanout = fanout
+		}
+	}
+}
+
+// WithSeed seeds the choice of peers, so rounds pick the same peers on every
+// run.
+func WithSeed(seed int64) Option {
+	return func(c *config) {
+		c.seed = seed
+	}
+}
+
+// WithClock sets the clock used for the times in Progress.
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+// Progress is what a Node knows about one of its peers.
+type Progress struct {
+	// Exchanges counts the successful exchanges, started by either side.
+	Exchanges int
+	// Failures counts the exchanges started by the node that failed.
+	Failures int
+	// LastExchange is the time of the last successful exchange.
+	LastExchange time.Time
+	// LastError is the error of the last exchange started by the node, nil
+	// if it succeeded.
+	LastError error
+	// Digest is the digest of the peer at the end of the last exchange.
+	Digest twoPSet.Digest
+	// Sent and Received count the operations sent to and received from the
+	// peer.
+	Sent     int
+	Received int
+	// InSync reports whether both had the same digest at the end of the last
+	// exchange.
+	InSync bool
+}
+
+// The kinds of requests, in the order of an exchange.
+const (
+	version = "version"
+	hashes  = "hashes"
+	pull    = "pull"
+	push    = "push"
+)
+
+// message is a request or response exchanged between nodes.
+type message[N, E any] struct {
+	Kind    string                   `json:"kind,omitempty"`
+	From    string                   `json:"from,omitempty"`
+	Version *replication.Version     `json:"version,omitempty"`
+	Hashes  *replication.HashRequest `json:"hashes,omitempty"`
+	Digests []twoPSet.Digest         `json:"digests,omitempty"`
+	Leaves  *replication.Leaves      `json:"leaves,omitempty"`
+	State   *replication.State[N, E] `json:"state,omitempty"`
+	// Digest is the digest of the sender, after merging in push requests
+	Digest twoPSet.Digest `json:"digest"`
+}
+
+// Node gossips the state of an ElementGraph with its peers. The Transport
+// must deliver the requests of the peers to Handle.
+type Node[N, E any] struct {
+	id        string
+	g         *crdt.ElementGraphOf[N, E]
+	transport Transport
+	config
+
+	// mu guards the fields below
+	mu       sync.Mutex
+	peers    []string
+	progress map[string]*Progress
+	rand     *rand.Rand
+}
+
+func NewNode[N, E any](id string, g *crdt.ElementGraphOf[N, E], transport Transport, peers []string, opts ...Option) *Node[N, E] {
+	n := &Node[N, E]{
+		id:        id,
+		g:         g,
+		transport: transport,
+		config: config{
+			lock:     &sync.Mutex{},
+			interval: time.Second,
+			fanout:   2,
+			seed:     time.Now().UnixNano(),
+			now:      time.Now,
+		},
+		progress: make(map[string]*Progress),
+	}
+
+	for _, opt := range opts {
+		opt(&n.config)
+	}
+
+	n.rand = rand.New(rand.NewSource(n.seed))
+	for _, peer := range peers {
+		n.AddPeer(peer)
+	}
+	return n
+}
+
+func (n *Node[N, E]) ID() string {
+	return n.id
+}
+
+// AddPeer adds a peer to gossip with.
+func (n *Node[N, E]) AddPeer(peer string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.progress[peer]; ok || peer == n.id {
+		return
+	}
+	n.peers = append(n.peers, peer)
+	n.progress[peer] = &Progress{}
+}
+
+// Peers returns the peers of the node.
+func (n *Node[N, E]) Peers() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.peers...)
+}
+
+// Progress returns what the node knows about each of its peers.
+func (n *Node[N, E]) Progress() map[string]Progress {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	progress := make(map[string]Progress, len(n.progress))
+	for peer, p := range n.progress {
+		progress[peer] = *p
+	}
+	return progress
+}
+
+// Run starts a round at the configured interval until the context is done.
+func (n *Node[N, E]) Run(ctx context.Context) error {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	for {
+		_ = n.Round(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Round gossips with as many peers as the fanout, picked at random, and
+// returns the errors of the failed exchanges.
+func (n *Node[N, E]) Round(ctx context.Context) error {
+	errs := make([]error, 0)
+	for _, peer := range n.partners() {
+		if err := n.Gossip(ctx, peer); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *Node[N, E]) partners() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	peers := append([]string(nil), n.peers...)
+	n.rand.Shuffle(len(peers), func(i, j int) {
+		peers[i], peers[j] = peers[j], peers[i]
+	})
+	if len(peers) > n.fanout {
+		peers = peers[:n.fanout]
+	}
+	return peers
+}
+
+// Gossip exchanges state with the peer through replication.Exchange, sending
+// its requests over the Transport.
+func (n *Node[N, E]) Gossip(ctx context.Context, peer string) error {
+	if peer == n.id {
+		return fmt.Errorf("gossip with %s: node can not gossip with itself", peer)
+	}
+	n.AddPeer(peer)
+
+	sent, received, digest, err := n.gossip(ctx, peer)
+	if err != nil {
+		err = fmt.Errorf("gossip with %s: %w", peer, err)
+	}
+
+	n.lock.Lock()
+	local := n.g.Digest()
+	n.lock.Unlock()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	p := n.progress[peer]
+	p.LastError = err
+	if err != nil {
+		p.Failures++
+		return err
+	}
+	n.exchanged(p, sent, received, digest, local)
+	return nil
+}
+
+func (n *Node[N, E]) gossip(ctx context.Context, peer string) (int, int, twoPSet.Digest, error) {
+	summary, err := replication.Exchange(n.g, n.lock, replication.Remote[N, E]{
+		Version: func() (replication.Version, error) {
+			n.lock.Lock()
+			local := n.g.Digest()
+			n.lock.Unlock()
+
+			var reply message[N, E]
+			if err := n.call(ctx, peer, message[N, E]{Kind: version, From: n.id, Digest: local}, &reply); err != nil {
+				return replication.Version{}, err
+			}
+			if reply.Version == nil {
+				return replication.Version{}, errors.New("reply without version")
+			}
+			return *reply.Version, nil
+		},
+		Probe: func(r replication.HashRequest) ([]twoPSet.Digest, error) {
+			var reply message[N, E]
+			err := n.call(ctx, peer, message[N, E]{Kind: hashes, From: n.id, Hashes: &r}, &reply)
+			return reply.Digests, err
+		},
+		Pull: func(leaves replication.Leaves) (replication.State[N, E], error) {
+			var reply message[N, E]
+			if err := n.call(ctx, peer, message[N, E]{Kind: pull, From: n.id, Leaves: &leaves}, &reply); err != nil {
+				return replication.State[N, E]{}, err
+			}
+			if reply.State == nil {
+				return replication.State[N, E]{}, errors.New("reply without state")
+			}
+			return *reply.State, nil
+		},
+		Push: func(out replication.State[N, E], digest twoPSet.Digest) (twoPSet.Digest, error) {
+			var reply message[N, E]
+			err := n.call(ctx, peer, message[N, E]{Kind: push, From: n.id, State: &out, Digest: digest}, &reply)
+			return reply.Digest, err
+		},
+	})
+
+	sent := summary.SentNodes + summary.SentEdges
+	received := summary.ReceivedNodes + summary.ReceivedEdges
+	return sent, received, summary.Digest, err
+}
+
+func (n *Node[N, E]) call(ctx context.Context, peer string, request message[N, E], reply *message[N, E]) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	data, err = n.transport.Call(ctx, peer, data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, reply)
+}
+
+// Handle answers a request of a peer, it is called by the Transport.
+func (n *Node[N, E]) Handle(ctx context.Context, request []byte) ([]byte, error) {
+	var in message[N, E]
+	if err := json.Unmarshal(request, &in); err != nil {
+		return nil, err
+	}
+
+	var out message[N, E]
+	var sent, received int
+
+	n.lock.Lock()
+	switch in.Kind {
+	case version:
+		v := replication.NewVersion(n.g)
+		out.Version = &v
+	case hashes:
+		if in.Hashes == nil {
+			n.lock.Unlock()
+			return nil, errors.New("hashes without request")
+		}
+
+		digests, err := replication.Hashes(n.g, *in.Hashes)
+		if err != nil {
+			n.lock.Unlock()
+			return nil, err
+		}
+		out.Digests = digests
+	case pull:
+		if in.Leaves == nil {
+			n.lock.Unlock()
+			return nil, errors.New("pull without leaves")
+		}
+
+		state := replication.Delta(n.g, *in.Leaves)
+		out.State = &state
+		sent = state.Nodes.Len() + state.Edges.Len()
+	case push:
+		if in.State == nil {
+			n.lock.Unlock()
+			return nil, errors.New("push without state")
+		}
+		if err := replication.Apply(n.g, *in.State); err != nil {
+			n.lock.Unlock()
+			return nil, err
+		}
+		received = in.State.Nodes.Len() + in.State.Edges.Len()
+	default:
+		n.lock.Unlock()
+		return nil, fmt.Errorf("unknown request %q", in.Kind)
+	}
+	out.Digest = n.g.Digest()
+	data, err := json.Marshal(out)
+	n.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if in.From != "" && in.From != n.id {
+		n.AddPeer(in.From)
+		n.mu.Lock()
+		p := n.progress[in.From]
+		switch in.Kind {
+		case version:
+			n.exchanged(p, 0, 0, in.Digest, out.Digest)
+		case pull:
+			p.Sent += sent
+		case push:
+			p.Received += received
+			p.Digest = in.Digest
+			p.InSync = in.Digest == out.Digest
+		}
+		n.mu.Unlock()
+	}
+	return data, nil
+}
+
+// exchanged records a successful exchange in the progress of a peer.
+func (n *Node[N, E]) exchanged(p *Progress, sent, received int, remote, local twoPSet.Digest) {
+	p.Exchanges++
+	p.LastExchange = n.now()
+	p.Sent += sent
+	p.Received += received
+	p.Digest = remote
+	p.InSync = remote == local
+}
@@ -0,0 +1,200 @@
+package gossip
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/tauki/crdt"
+	"github.com/tauki/crdt/graph"
+	"testing"
+	"time"
+)
+
+type cluster struct {
+	network *MemoryNetwork
+	graphs  []*crdt.ElementGraph
+	nodes   []*Node[[]byte, []byte]
+}
+
+func newCluster(size int) *cluster {
+	c := &cluster{network: NewMemoryNetwork()}
+	ids := make([]string, size)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("replica-%d", i)
+	}
+
+	for i, id := range ids {
+		g := crdt.NewElementGraph()
+		n := NewNode(id, g, c.network.Endpoint(id), ids, WithSeed(int64(i)))
+		c.network.Listen(id, n.Handle)
+		c.graphs = append(c.graphs, g)
+		c.nodes = append(c.nodes, n)
+	}
+	return c
+}
+
+func (c *cluster) round() {
+	for _, n := range c.nodes {
+		_ = n.Round(context.Background())
+	}
+}
+
+func (c *cluster) converged(graphs []*crdt.ElementGraph) bool {
+	for _, g := range graphs[1:] {
+		if g.Digest() != graphs[0].Digest() {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNode_ClusterConverges(t *testing.T) {
+	c := newCluster(12)
+	for i, g := range c.graphs {
+		node := graph.NewNode(uuid.New(), []byte{byte(i)})
+		g.AddNode(node)
+		g.AddEdge(graph.NewEdge(uuid.New(), node, node))
+	}
+
+	rounds := 0
+	for ; rounds < 20 && !c.converged(c.graphs); rounds++ {
+		c.round()
+	}
+	assert.True(t, c.converged(c.graphs), "not converged after %d rounds", rounds)
+
+	for _, g := range c.graphs {
+		assert.Equal(t, 12, g.Graph.Len())
+		assert.Len(t, g.Graph.Edges(), 12)
+		assert.Equal(t, c.graphs[0].GraphDigest(), g.GraphDigest())
+	}
+
+	exchanges := 0
+	for _, n := range c.nodes {
+		assert.Len(t, n.Peers(), 11)
+		for _, p := range n.Progress() {
+			exchanges += p.Exchanges
+			assert.NoError(t, p.LastError)
+		}
+	}
+	assert.NotZero(t, exchanges)
+}
+
+func TestNode_Partition(t *testing.T) {
+	c := newCluster(4)
+	for _, a := range []string{"replica-0", "replica-1"} {
+		for _, b := range []string{"replica-2", "replica-3"} {
+			c.network.Cut(a, b)
+		}
+	}
+
+	for _, g := range c.graphs {
+		g.AddNode(graph.NewNode(uuid.New(), nil))
+	}
+	for i := 0; i < 10; i++ {
+		c.round()
+	}
+	assert.True(t, c.converged(c.graphs[:2]))
+	assert.True(t, c.converged(c.graphs[2:]))
+	assert.False(t, c.converged(c.graphs))
+	assert.NotZero(t, c.nodes[0].Progress()["replica-2"].Failures+c.nodes[0].Progress()["replica-3"].Failures)
+
+	c.network.Heal()
+	for i := 0; i < 10 && !c.converged(c.graphs); i++ {
+		c.round()
+	}
+	assert.True(t, c.converged(c.graphs))
+	assert.Equal(t, 4, c.graphs[0].Graph.Len())
+}
+
+func TestNode_Gossip(t *testing.T) {
+	c := newCluster(2)
+	c.graphs[0].AddNode(graph.NewNode(uuid.New(), nil))
+	time.Sleep(1)
+	c.graphs[1].AddNode(graph.NewNode(uuid.New(), nil))
+
+	assert.NoError(t, c.nodes[0].Gossip(context.Background(), "replica-1"))
+	assert.Equal(t, c.graphs[0].Digest(), c.graphs[1].Digest())
+
+	p := c.nodes[0].Progress()["replica-1"]
+	assert.Equal(t, 1, p.Exchanges)
+	assert.Equal(t, 1, p.Sent)
+	assert.Equal(t, 1, p.Received)
+	assert.True(t, p.InSync)
+	assert.Equal(t, c.graphs[1].Digest(), p.Digest)
+
+	remote := c.nodes[1].Progress()["replica-0"]
+	assert.Equal(t, 1, remote.Exchanges)
+	assert.Equal(t, 1, remote.Received)
+	assert.True(t, remote.InSync)
+
+	assert.NoError(t, c.nodes[0].Gossip(context.Background(), "replica-1"))
+	p = c.nodes[0].Progress()["replica-1"]
+	assert.Equal(t, 2, p.Exchanges)
+	assert.Equal(t, 1, p.Sent)
+}
+
+func TestNode_Gossip_Errors(t *testing.T) {
+	c := newCluster(2)
+
+	err := c.nodes[0].Gossip(context.Background(), "replica-9")
+	assert.ErrorIs(t, err, ErrUnreachable)
+	assert.Equal(t, 1, c.nodes[0].Progress()["replica-9"].Failures)
+	assert.Error(t, c.nodes[0].Gossip(context.Background(), "replica-0"))
+
+	_, err = c.nodes[1].Handle(context.Background(), []byte(`{"kind":"push","from":"x"}`))
+	assert.Error(t, err)
+	_, err = c.nodes[1].Handle(context.Background(), []byte(`{"kind":"hashes"}`))
+	assert.Error(t, err)
+	_, err = c.nodes[1].Handle(context.Background(), []byte(`{"kind":"hashes","hashes":{"tree":"nodes","level":5}}`))
+	assert.Error(t, err)
+	_, err = c.nodes[1].Handle(context.Background(), []byte(`{"kind":"pull"}`))
+	assert.Error(t, err)
+	_, err = c.nodes[1].Handle(context.Background(), []byte(`{"kind":"dance"}`))
+	assert.Error(t, err)
+	_, err = c.nodes[1].Handle(context.Background(), []byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestNode_Run(t *testing.T) {
+	c := newCluster(3)
+	for _, g := range c.graphs {
+		g.AddNode(graph.NewNode(uuid.New(), nil))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, len(c.nodes))
+	for _, n := range c.nodes {
+		n := n
+		n.interval = time.Millisecond
+		go func() {
+			done <- n.Run(ctx)
+		}()
+	}
+	for range c.nodes {
+		assert.ErrorIs(t, <-done, context.DeadlineExceeded)
+	}
+	assert.True(t, c.converged(c.graphs))
+}
+
+func TestWithInterval(t *testing.T) {
+	n := NewNode("a", crdt.NewElementGraph(), NewMemoryNetwork().Endpoint("a"), nil, WithInterval(0))
+	assert.Equal(t, time.Second, n.interval)
+
+	n = NewNode("a", crdt.NewElementGraph(), NewMemoryNetwork().Endpoint("a"), nil, WithInterval(-time.Second), WithInterval(time.Millisecond))
+	assert.Equal(t, time.Millisecond, n.interval)
+}
+
+func TestWithFanout(t *testing.T) {
+	network := NewMemoryNetwork()
+	peers := []string{"a", "b", "c"}
+	for _, fanout := range []int{-1, 0} {
+		n := NewNode("a", crdt.NewElementGraph(), network.Endpoint("a"), peers, WithFanout(fanout))
+		assert.Equal(t, 2, n.fanout)
+		assert.Len(t, n.partners(), 2)
+	}
+
+	n := NewNode("a", crdt.NewElementGraph(), network.Endpoint("a"), peers, WithFanout(1))
+	assert.Len(t, n.partners(), 1)
+}
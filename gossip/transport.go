@@ -0,0 +1,99 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnreachable is returned by a Transport when the peer can not be reached.
+var ErrUnreachable = errors.New("peer unreachable")
+
+// Transport carries the requests of a Node to its peers. A transport also
+// delivers the requests of the peers to Node.Handle and returns its response.
+type Transport interface {
+	// Call sends the request to the peer and waits for its response.
+	Call(ctx context.Context, peer string, request []byte) ([]byte, error)
+}
+
+// Handler answers a request from a peer, see Node.Handle.
+type Handler func(ctx context.Context, request []byte) ([]byte, error)
+
+// MemoryNetwork is an in-process Transport for tests. Every Node gets its own
+// Endpoint and registers its Handle with Listen. Links between peers can be
+// cut to simulate partitions.
+type MemoryNetwork struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	cut      map[[2]string]bool
+}
+
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{
+		handlers: make(map[string]Handler),
+		cut:      make(map[[2]string]bool),
+	}
+}
+
+// Listen delivers the requests sent to the peer to the handler.
+func (m *MemoryNetwork) Listen(peer string, handler Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[peer] = handler
+}
+
+// Leave removes the peer from the network.
+func (m *MemoryNetwork) Leave(peer string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.handlers, peer)
+}
+
+// Cut makes requests between the two peers fail, in both directions.
+func (m *MemoryNetwork) Cut(a, b string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cut[link(a, b)] = true
+}
+
+// Heal restores every cut link.
+func (m *MemoryNetwork) Heal() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cut = make(map[[2]string]bool)
+}
+
+// Endpoint returns the Transport of the given peer.
+func (m *MemoryNetwork) Endpoint(peer string) Transport {
+	return &endpoint{network: m, peer: peer}
+}
+
+type endpoint struct {
+	network *MemoryNetwork
+	peer    string
+}
+
+func (e *endpoint) Call(ctx context.Context, peer string, request []byte) ([]byte, error) {
+	e.network.mu.RLock()
+	handler, ok := e.network.handlers[peer]
+	cut := e.network.cut[link(e.peer, peer)]
+	e.network.mu.RUnlock()
+
+	if !ok || cut {
+		return nil, fmt.Errorf("%w: %s", ErrUnreachable, peer)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// copy the request, so the peers do not share memory
+	return handler(ctx, append([]byte(nil), request...))
+}
+
+func link(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
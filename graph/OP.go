@@ -2,25 +2,31 @@ package graph
 
 import "github.com/google/uuid"
 
-func (g *T) AddNode(node *Node) bool {
+func (g *TOf[N, E]) AddNode(node *NodeOf[N, E]) bool {
 	if g.NodeExists(node) {
 		return false
 	}
 
 	g.List[node.ID] = node
+	for _, e := range node.Edges {
+		g.indexEdge(e)
+	}
 	return true
 }
 
-func (g *T) RemoveNode(node *Node) bool {
+func (g *TOf[N, E]) RemoveNode(node *NodeOf[N, E]) bool {
 	if g.NodeExists(node) {
-		for _, n := range g.List {
-			for k, e := range n.Edges {
-				if e.To.ID == node.ID {
-					delete(n.Edges, k)
-				}
+		for k, e := range g.In[node.ID] {
+			if from, ok := g.List[e.From.ID]; ok {
+				delete(from.Edges, k)
 			}
 		}
 
+		for k, e := range g.List[node.ID].Edges {
+			delete(g.In[e.To.ID], k)
+		}
+
+		delete(g.In, node.ID)
 		delete(g.List, node.ID)
 		return true
 	}
@@ -28,29 +34,33 @@ func (g *T) RemoveNode(node *Node) bool {
 	return false
 }
 
-func (g *T) NodeExists(node *Node) bool {
+func (g *TOf[N, E]) NodeExists(node *NodeOf[N, E]) bool {
 	_, ok := g.List[node.ID]
 	return ok
 }
 
-func (g *T) GetNode(id uuid.UUID) *Node {
+func (g *TOf[N, E]) GetNode(id uuid.UUID) *NodeOf[N, E] {
 	node, _ := g.List[id]
 	return node
 }
 
-func (g *T) AddEdge(edge *Edge) bool {
+func (g *TOf[N, E]) AddEdge(edge *EdgeOf[N, E]) bool {
 	if g.EdgeExists(edge) ||
 		!g.NodeExists(edge.To) ||
-		!g.NodeExists(edge.From) {
+		!g.NodeExists(edge.From) ||
+		(g.simple && g.hasParallel(edge)) {
 		return false
 	}
 	g.List[edge.From.ID].Edges[edge.ID] = edge
+	g.indexEdge(edge)
 	return true
 }
 
-func (g *T) RemoveEdge(edge *Edge) bool {
+func (g *TOf[N, E]) RemoveEdge(edge *EdgeOf[N, E]) bool {
 	if g.NodeExists(edge.From) {
 		if g.EdgeExists(edge) {
+			stored := g.List[edge.From.ID].Edges[edge.ID]
+			delete(g.In[stored.To.ID], edge.ID)
 			delete(g.List[edge.From.ID].Edges, edge.ID)
 			return true
 		}
@@ -59,10 +69,71 @@ func (g *T) RemoveEdge(edge *Edge) bool {
 	return false
 }
 
-func (g *T) EdgeExists(edge *Edge) bool {
+// Copy returns a copy of the graph with new nodes and edges, so either graph
+// can be changed without affecting the other. Payloads are not copied.
+func (g *TOf[N, E]) Copy() *TOf[N, E] {
+	c := &TOf[N, E]{
+		List:   make(NodeSetOf[N, E], len(g.List)),
+		In:     make(map[uuid.UUID]EdgeSetOf[N, E], len(g.In)),
+		config: g.config,
+	}
+	for id, node := range g.List {
+		c.List[id] = NewNodeOf[N, E](id, node.Payload)
+	}
+	for _, node := range g.List {
+		for id, edge := range node.Edges {
+			e := *edge
+			e.From = c.List[node.ID]
+			if to, ok := c.List[edge.To.ID]; ok {
+				e.To = to
+			}
+			c.List[node.ID].Edges[id] = &e
+			c.indexEdge(&e)
+		}
+	}
+	return c
+}
+
+func (g *TOf[N, E]) EdgeExists(edge *EdgeOf[N, E]) bool {
 	if node, ok := g.List[edge.From.ID]; ok {
 		_, ok = node.Edges[edge.ID]
 		return ok
 	}
 	return false
 }
+
+// InEdges returns the edges pointing to the node.
+func (g *TOf[N, E]) InEdges(node *NodeOf[N, E]) EdgeSetOf[N, E] {
+	if edges, ok := g.In[node.ID]; ok {
+		return edges
+	}
+	return EdgeSetOf[N, E]{}
+}
+
+func (g *TOf[N, E]) indexEdge(edge *EdgeOf[N, E]) {
+	in, ok := g.In[edge.To.ID]
+	if !ok {
+		in = make(EdgeSetOf[N, E])
+		g.In[edge.To.ID] = in
+	}
+	in[edge.ID] = edge
+}
+
+// hasParallel reports whether the graph has another edge with the same label
+// between the endpoints of the edge, in the same direction unless one of them
+// is undirected.
+func (g *TOf[N, E]) hasParallel(edge *EdgeOf[N, E]) bool {
+	for _, e := range g.List[edge.From.ID].Edges {
+		if e.To.ID == edge.To.ID && e.Label == edge.Label {
+			return true
+		}
+	}
+
+	for _, e := range g.In[edge.From.ID] {
+		if e.From.ID == edge.To.ID && e.Label == edge.Label &&
+			(g.Undirected(e) || g.Undirected(edge)) {
+			return true
+		}
+	}
+	return false
+}
@@ -103,6 +103,31 @@ func TestT_AddEdge(t *testing.T) {
 	assert.Equal(t, edge, g.List[edge.From.ID].Edges[edge.ID])
 }
 
+func TestT_Copy(t *testing.T) {
+	g := NewOf[[]byte, []byte](WithSimpleGraph())
+	node1 := NewNode(uuid.New(), []byte("a"))
+	node2 := NewNode(uuid.New(), []byte("b"))
+	g.AddNode(node1)
+	g.AddNode(node2)
+	edge := NewEdge(uuid.New(), node1, node2)
+	g.AddEdge(edge)
+
+	c := g.Copy()
+	assert.True(t, c.Simple())
+	assert.Equal(t, Digest[[]byte, []byte](g), Digest[[]byte, []byte](c))
+
+	copied := c.List[node1.ID].Edges[edge.ID]
+	assert.NotSame(t, edge, copied)
+	assert.Same(t, c.GetNode(node1.ID), copied.From)
+	assert.Same(t, c.GetNode(node2.ID), copied.To)
+	assert.Same(t, copied, c.InEdges(node2)[edge.ID])
+
+	c.RemoveNode(c.GetNode(node2.ID))
+	assert.Equal(t, 2, g.Len())
+	assert.True(t, g.EdgeExists(edge))
+	assert.Len(t, g.InEdges(node2), 1)
+}
+
 func TestT_AddEdge_AlreadyExists(t *testing.T) {
 	g := New()
 	node1 := NewNode(uuid.New(), []byte{})
@@ -195,3 +220,67 @@ func TestT_EdgeExists(t *testing.T) {
 
 	assert.True(t, g.EdgeExists(edge))
 }
+
+func TestT_InEdges(t *testing.T) {
+	g := New()
+	node1 := NewNode(uuid.New(), []byte{})
+	node2 := NewNode(uuid.New(), []byte{})
+	node3 := NewNode(uuid.New(), []byte{})
+
+	g.AddNode(node1)
+	g.AddNode(node2)
+	g.AddNode(node3)
+	assert.Empty(t, g.InEdges(node2))
+
+	edge12 := NewEdge(uuid.New(), node1, node2)
+	edge32 := NewEdge(uuid.New(), node3, node2)
+	g.AddEdge(edge12)
+	g.AddEdge(edge32)
+
+	assert.Equal(t, EdgeSet{edge12.ID: edge12, edge32.ID: edge32}, g.InEdges(node2))
+	assert.Empty(t, g.InEdges(node1))
+
+	g.RemoveEdge(NewEdge(edge12.ID, node1, node2))
+	assert.Equal(t, EdgeSet{edge32.ID: edge32}, g.InEdges(node2))
+}
+
+func TestT_RemoveNode_UpdatesInEdges(t *testing.T) {
+	g := New()
+	node1 := NewNode(uuid.New(), []byte{})
+	node2 := NewNode(uuid.New(), []byte{})
+	node3 := NewNode(uuid.New(), []byte{})
+
+	g.AddNode(node1)
+	g.AddNode(node2)
+	g.AddNode(node3)
+
+	edge12 := NewEdge(uuid.New(), node1, node2)
+	edge23 := NewEdge(uuid.New(), node2, node3)
+	edge22 := NewEdge(uuid.New(), node2, node2)
+	g.AddEdge(edge12)
+	g.AddEdge(edge23)
+	g.AddEdge(edge22)
+
+	assert.True(t, g.RemoveNode(node2))
+	assert.Empty(t, g.List[node1.ID].Edges)
+	assert.Empty(t, g.InEdges(node3))
+	assert.NotContains(t, g.In, node2.ID)
+}
+
+func TestT_AddNode_IndexesExistingEdges(t *testing.T) {
+	g := New()
+	node1 := NewNode(uuid.New(), []byte{})
+	node2 := NewNode(uuid.New(), []byte{})
+
+	g.AddNode(node1)
+	g.AddNode(node2)
+
+	edge := NewEdge(uuid.New(), node1, node2)
+	g.AddEdge(edge)
+	g.RemoveNode(node1)
+	assert.Empty(t, g.InEdges(node2))
+
+	g.AddNode(node1)
+	assert.True(t, g.EdgeExists(edge))
+	assert.Equal(t, EdgeSet{edge.ID: edge}, g.InEdges(node2))
+}
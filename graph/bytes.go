@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"github.com/google/uuid"
+)
+
+// The types below are the graph with []byte payloads on nodes and edges.
+
+type Graph = GraphOf[[]byte, []byte]
+type NodeSet = NodeSetOf[[]byte, []byte]
+type EdgeSet = EdgeSetOf[[]byte, []byte]
+type Node = NodeOf[[]byte, []byte]
+type Edge = EdgeOf[[]byte, []byte]
+type T = TOf[[]byte, []byte]
+type Components = ComponentsOf[[]byte, []byte]
+type CycleError = CycleErrorOf[[]byte, []byte]
+type ReachabilityIndex = ReachabilityIndexOf[[]byte, []byte]
+
+func NewNode(id uuid.UUID, payload []byte) *Node {
+	return NewNodeOf[[]byte, []byte](id, payload)
+}
+
+func NewEdge(id uuid.UUID, from, to *Node) *Edge {
+	return NewEdgeOf(id, from, to)
+}
+
+func NewUndirectedEdge(id uuid.UUID, a, b *Node) *Edge {
+	return NewUndirectedEdgeOf(id, a, b)
+}
+
+func New(opts ...Option) *T {
+	return NewOf[[]byte, []byte](opts...)
+}
+
+// NewKeyedNode returns a node with the id derived by NodeID.
+func NewKeyedNode(namespace uuid.UUID, key string, payload []byte) *Node {
+	return NewKeyedNodeOf[[]byte, []byte](namespace, key, payload)
+}
+
+// NewKeyedEdge returns an edge with the given label and the id derived by
+// EdgeID from its endpoints and label.
+func NewKeyedEdge(from, to *Node, label string) *Edge {
+	return NewKeyedEdgeOf(from, to, label)
+}
@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"github.com/google/uuid"
+)
+
+// condensationNamespace is used to derive the ids of the nodes and edges of a
+// condensation from the ids of the nodes they stand for.
+var condensationNamespace = uuid.MustParse("5b0e7a52-8a3c-4b8e-9a43-3c1f0d8f6a11")
+
+// ComponentsOf is a partition of the nodes of a graph.
+type ComponentsOf[N, E any] struct {
+	// Membership maps the id of every node to its component in Members.
+	Membership map[uuid.UUID]int
+	// Members lists the nodes of every component, ordered by id.
+	Members [][]*NodeOf[N, E]
+}
+
+func newComponents[N, E any](members [][]*NodeOf[N, E]) *ComponentsOf[N, E] {
+	c := &ComponentsOf[N, E]{
+		Membership: make(map[uuid.UUID]int),
+		Members:    members,
+	}
+	for i, nodes := range members {
+		for _, n := range nodes {
+			c.Membership[n.ID] = i
+		}
+	}
+	return c
+}
+
+// ID returns an id for the i-th component derived from the ids of its nodes,
+// it is the id of the component in the graph returned by Condensation.
+func (c *ComponentsOf[N, E]) ID(i int) uuid.UUID {
+	name := make([]byte, 0, len(c.Members[i])*16)
+	for _, n := range c.Members[i] {
+		name = append(name, n.ID[:]...)
+	}
+	return uuid.NewSHA1(condensationNamespace, name)
+}
+
+// Len returns the number of components.
+func (c *ComponentsOf[N, E]) Len() int {
+	return len(c.Members)
+}
+
+// StronglyConnectedComponents groups the nodes that can all reach each other,
+// using Tarjan's algorithm. The components are in topological order: edges
+// between different components point to a later one.
+func StronglyConnectedComponents[N, E any](g GraphOf[N, E]) *ComponentsOf[N, E] {
+	components := stronglyConnected(g)
+	for i, j := 0, len(components)-1; i < j; i, j = i+1, j-1 {
+		components[i], components[j] = components[j], components[i]
+	}
+	return newComponents(components)
+}
+
+// WeaklyConnectedComponents groups the nodes that are connected when the
+// direction of the edges is ignored. The components are ordered by their
+// smallest node id.
+func WeaklyConnectedComponents[N, E any](g GraphOf[N, E]) *ComponentsOf[N, E] {
+	visited := make(map[uuid.UUID]bool)
+	components := make([][]*NodeOf[N, E], 0)
+
+	for _, n := range SortNodes(g.Nodes()) {
+		if visited[n.ID] {
+			continue
+		}
+
+		visited[n.ID] = true
+		component := []*NodeOf[N, E]{n}
+		for i := 0; i < len(component); i++ {
+			id := component[i].ID
+			neighbors := append(g.OutNeighbors(id), g.InNeighbors(id)...)
+			for _, next := range neighbors {
+				if !visited[next.ID] {
+					visited[next.ID] = true
+					component = append(component, next)
+				}
+			}
+		}
+		components = append(components, SortNodes(component))
+	}
+
+	return newComponents(components)
+}
+
+// Condensation returns the graph of the strongly connected components of g,
+// which is always acyclic, together with the components. Every component is
+// a node with the id given by Components.ID, and two components are connected
+// by a single edge if any of their nodes are. The ids of the edges are derived
+// from the ids of the components, so they are the same on every call.
+func Condensation[N, E any](g GraphOf[N, E]) (*TOf[N, E], *ComponentsOf[N, E]) {
+	components := StronglyConnectedComponents(g)
+
+	condensed := NewOf[N, E](WithDeterministicOrder())
+	nodes := make([]*NodeOf[N, E], components.Len())
+	for i := range components.Members {
+		var payload N
+		nodes[i] = NewNodeOf[N, E](components.ID(i), payload)
+		condensed.AddNode(nodes[i])
+	}
+
+	for i, members := range components.Members {
+		for _, n := range members {
+			for _, next := range g.OutNeighbors(n.ID) {
+				j := components.Membership[next.ID]
+				if i == j {
+					continue
+				}
+
+				from, to := nodes[i], nodes[j]
+				name := append(append([]byte{}, from.ID[:]...), to.ID[:]...)
+				condensed.AddEdge(NewEdgeOf(uuid.NewSHA1(condensationNamespace, name), from, to))
+			}
+		}
+	}
+
+	return condensed, components
+}
@@ -0,0 +1,88 @@
+package graph
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// newClusters builds two cycles a0 -> a1 -> a2 -> a0 and b0 <-> b1 with an
+// edge a2 -> b0, and an isolated node.
+func newClusters() (*T, []*Node, []*Node, *Node) {
+	g := New()
+	a := newChain(g, 3)
+	g.AddEdge(NewEdge(uuid.New(), a[2], a[0]))
+
+	b := newChain(g, 2)
+	g.AddEdge(NewEdge(uuid.New(), b[1], b[0]))
+	g.AddEdge(NewEdge(uuid.New(), a[2], b[0]))
+
+	isolated := NewNode(uuid.New(), []byte{})
+	g.AddNode(isolated)
+
+	return g, a, b, isolated
+}
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	g, a, b, isolated := newClusters()
+
+	components := StronglyConnectedComponents(g)
+	assert.Equal(t, 3, components.Len())
+	assert.Len(t, components.Membership, 6)
+
+	assert.Equal(t, SortNodes(a), components.Members[components.Membership[a[0].ID]])
+	assert.Equal(t, SortNodes(b), components.Members[components.Membership[b[0].ID]])
+	assert.Equal(t, []*Node{isolated}, components.Members[components.Membership[isolated.ID]])
+	assert.Less(t, components.Membership[a[0].ID], components.Membership[b[0].ID])
+}
+
+func TestStronglyConnectedComponents_Empty(t *testing.T) {
+	components := StronglyConnectedComponents(New())
+	assert.Equal(t, 0, components.Len())
+	assert.Empty(t, components.Membership)
+}
+
+func TestWeaklyConnectedComponents(t *testing.T) {
+	g, a, b, isolated := newClusters()
+
+	components := WeaklyConnectedComponents(g)
+	assert.Equal(t, 2, components.Len())
+
+	joined := components.Members[components.Membership[a[0].ID]]
+	assert.ElementsMatch(t, append(a, b...), joined)
+	assert.Equal(t, SortNodes(append([]*Node{}, joined...)), joined)
+	assert.Equal(t, []*Node{isolated}, components.Members[components.Membership[isolated.ID]])
+	assert.Less(t, components.Members[0][0].ID.String(), components.Members[1][0].ID.String())
+}
+
+func TestCondensation(t *testing.T) {
+	g, a, b, isolated := newClusters()
+
+	condensed, components := Condensation(g)
+	assert.Equal(t, 3, condensed.Len())
+	assert.False(t, HasCycle(condensed))
+
+	clusterA := components.ID(components.Membership[a[0].ID])
+	clusterB := components.ID(components.Membership[b[0].ID])
+	single := components.ID(components.Membership[isolated.ID])
+	for _, id := range []uuid.UUID{clusterA, clusterB, single} {
+		assert.NotNil(t, condensed.GetNode(id))
+	}
+
+	edges := condensed.Edges()
+	assert.Len(t, edges, 1)
+	assert.Equal(t, clusterA, edges[0].From.ID)
+	assert.Equal(t, clusterB, edges[0].To.ID)
+
+	again, _ := Condensation(g)
+	assert.Equal(t, condensed.Nodes(), again.Nodes())
+	assert.Equal(t, edges[0].ID, again.Edges()[0].ID)
+}
+
+func TestComponents_ID(t *testing.T) {
+	g, _, _, _ := newClusters()
+	components := WeaklyConnectedComponents(g)
+
+	assert.NotEqual(t, components.ID(0), components.ID(1))
+	assert.Equal(t, components.ID(0), WeaklyConnectedComponents(g).ID(0))
+}
@@ -0,0 +1,209 @@
+package graph
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// CycleErrorOf is returned by TopologicalSort when the graph is not acyclic.
+type CycleErrorOf[N, E any] struct {
+	Cycle []*NodeOf[N, E]
+}
+
+func (e *CycleErrorOf[N, E]) Error() string {
+	ids := make([]string, 0, len(e.Cycle)+1)
+	for _, n := range e.Cycle {
+		ids = append(ids, n.ID.String())
+	}
+	if len(e.Cycle) > 0 {
+		ids = append(ids, e.Cycle[0].ID.String())
+	}
+	return fmt.Sprintf("graph has a cycle: %s", strings.Join(ids, " -> "))
+}
+
+// TopologicalSort returns the nodes so that every edge points from a node to
+// a later one. Nodes without an order between them are ordered by id. If the
+// graph has a cycle, a *CycleError naming one of them is returned.
+func TopologicalSort[N, E any](g GraphOf[N, E]) ([]*NodeOf[N, E], error) {
+	nodes := g.Nodes()
+
+	inDegree := make(map[uuid.UUID]int, len(nodes))
+	for _, n := range nodes {
+		for _, next := range g.OutNeighbors(n.ID) {
+			inDegree[next.ID]++
+		}
+	}
+
+	ready := &nodeHeap[N, E]{}
+	for _, n := range nodes {
+		if inDegree[n.ID] == 0 {
+			heap.Push(ready, n)
+		}
+	}
+
+	order := make([]*NodeOf[N, E], 0, len(nodes))
+	for ready.Len() > 0 {
+		n := heap.Pop(ready).(*NodeOf[N, E])
+		order = append(order, n)
+
+		for _, next := range g.OutNeighbors(n.ID) {
+			inDegree[next.ID]--
+			if inDegree[next.ID] == 0 {
+				heap.Push(ready, next)
+			}
+		}
+	}
+
+	if len(order) < len(nodes) {
+		return nil, &CycleErrorOf[N, E]{Cycle: FindCycles(g)[0]}
+	}
+
+	return order, nil
+}
+
+// HasCycle reports whether the graph has a cycle, including self-pointing
+// edges.
+func HasCycle[N, E any](g GraphOf[N, E]) bool {
+	for _, component := range stronglyConnected(g) {
+		if isCyclic(g, component) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindCycles returns one cycle for every group of nodes that are on cycles
+// with each other (every strongly connected component with a cycle). Each
+// cycle lists its nodes in edge order, the last node has an edge to the first.
+func FindCycles[N, E any](g GraphOf[N, E]) [][]*NodeOf[N, E] {
+	cycles := make([][]*NodeOf[N, E], 0)
+	for _, component := range stronglyConnected(g) {
+		if isCyclic(g, component) {
+			cycles = append(cycles, cycleIn(g, component))
+		}
+	}
+	return cycles
+}
+
+func isCyclic[N, E any](g GraphOf[N, E], component []*NodeOf[N, E]) bool {
+	if len(component) > 1 {
+		return true
+	}
+
+	for _, next := range g.OutNeighbors(component[0].ID) {
+		if next.ID == component[0].ID {
+			return true
+		}
+	}
+	return false
+}
+
+// cycleIn walks a cycle through the nodes of a strongly connected component,
+// starting at the node with the smallest id and following the smallest
+// neighbor inside the component.
+func cycleIn[N, E any](g GraphOf[N, E], component []*NodeOf[N, E]) []*NodeOf[N, E] {
+	members := make(map[uuid.UUID]bool, len(component))
+	for _, n := range component {
+		members[n.ID] = true
+	}
+
+	index := make(map[uuid.UUID]int)
+	path := make([]*NodeOf[N, E], 0)
+	for n := component[0]; ; {
+		if i, ok := index[n.ID]; ok {
+			return path[i:]
+		}
+		index[n.ID] = len(path)
+		path = append(path, n)
+
+		for _, next := range SortNodes(g.OutNeighbors(n.ID)) {
+			if members[next.ID] {
+				n = next
+				break
+			}
+		}
+	}
+}
+
+// stronglyConnected returns the strongly connected components of the graph
+// using Tarjan's algorithm. Nodes are visited ordered by id, every component
+// is ordered by id and the components are in reverse topological order.
+func stronglyConnected[N, E any](g GraphOf[N, E]) [][]*NodeOf[N, E] {
+	index := make(map[uuid.UUID]int)
+	low := make(map[uuid.UUID]int)
+	onStack := make(map[uuid.UUID]bool)
+	stack := make([]*NodeOf[N, E], 0)
+	components := make([][]*NodeOf[N, E], 0)
+
+	var visit func(n *NodeOf[N, E])
+	visit = func(n *NodeOf[N, E]) {
+		index[n.ID] = len(index)
+		low[n.ID] = index[n.ID]
+		stack = append(stack, n)
+		onStack[n.ID] = true
+
+		for _, next := range SortNodes(g.OutNeighbors(n.ID)) {
+			if _, ok := index[next.ID]; !ok {
+				visit(next)
+				if low[next.ID] < low[n.ID] {
+					low[n.ID] = low[next.ID]
+				}
+			} else if onStack[next.ID] && index[next.ID] < low[n.ID] {
+				low[n.ID] = index[next.ID]
+			}
+		}
+
+		if low[n.ID] != index[n.ID] {
+			return
+		}
+
+		component := make([]*NodeOf[N, E], 0)
+		for {
+			m := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[m.ID] = false
+			component = append(component, m)
+			if m.ID == n.ID {
+				break
+			}
+		}
+		components = append(components, SortNodes(component))
+	}
+
+	for _, n := range SortNodes(g.Nodes()) {
+		if _, ok := index[n.ID]; !ok {
+			visit(n)
+		}
+	}
+
+	return components
+}
+
+// nodeHeap is a min-heap of nodes ordered by id.
+type nodeHeap[N, E any] []*NodeOf[N, E]
+
+func (h nodeHeap[N, E]) Len() int {
+	return len(h)
+}
+
+func (h nodeHeap[N, E]) Less(i, j int) bool {
+	return bytes.Compare(h[i].ID[:], h[j].ID[:]) < 0
+}
+
+func (h nodeHeap[N, E]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *nodeHeap[N, E]) Push(x interface{}) {
+	*h = append(*h, x.(*NodeOf[N, E]))
+}
+
+func (h *nodeHeap[N, E]) Pop() interface{} {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newChain(g *T, n int) []*Node {
+	nodes := make([]*Node, n)
+	for i := range nodes {
+		nodes[i] = NewNode(uuid.New(), []byte{})
+		g.AddNode(nodes[i])
+		if i > 0 {
+			g.AddEdge(NewEdge(uuid.New(), nodes[i-1], nodes[i]))
+		}
+	}
+	return nodes
+}
+
+func TestTopologicalSort(t *testing.T) {
+	g := New()
+	nodes := newChain(g, 5)
+	g.AddEdge(NewEdge(uuid.New(), nodes[0], nodes[3]))
+
+	order, err := TopologicalSort(g)
+	assert.NoError(t, err)
+	assert.Equal(t, nodes, order)
+}
+
+func TestTopologicalSort_OrdersIndependentNodesByID(t *testing.T) {
+	g := New()
+	for i := 0; i < 10; i++ {
+		g.AddNode(NewNode(uuid.New(), []byte{}))
+	}
+
+	order, err := TopologicalSort(g)
+	assert.NoError(t, err)
+	assert.Equal(t, SortNodes(g.Nodes()), order)
+}
+
+func TestTopologicalSort_Empty(t *testing.T) {
+	order, err := TopologicalSort(New())
+	assert.NoError(t, err)
+	assert.Empty(t, order)
+}
+
+func TestTopologicalSort_Cycle(t *testing.T) {
+	g := New()
+	nodes := newChain(g, 4)
+	g.AddEdge(NewEdge(uuid.New(), nodes[3], nodes[1]))
+
+	order, err := TopologicalSort(g)
+	assert.Nil(t, order)
+
+	cycleErr, ok := err.(*CycleError)
+	assert.True(t, ok)
+	assert.ElementsMatch(t, nodes[1:], cycleErr.Cycle)
+	assert.Contains(t, err.Error(), "graph has a cycle: ")
+	assert.Contains(t, err.Error(), nodes[2].ID.String())
+}
+
+func TestHasCycle(t *testing.T) {
+	g := New()
+	nodes := newChain(g, 3)
+	assert.False(t, HasCycle(g))
+
+	g.AddEdge(NewEdge(uuid.New(), nodes[2], nodes[0]))
+	assert.True(t, HasCycle(g))
+}
+
+func TestHasCycle_SelfPointingEdge(t *testing.T) {
+	g := New()
+	node := NewNode(uuid.New(), []byte{})
+	g.AddNode(node)
+	assert.False(t, HasCycle(g))
+
+	g.AddEdge(NewEdge(uuid.New(), node, node))
+	assert.True(t, HasCycle(g))
+	assert.Equal(t, [][]*Node{{node}}, FindCycles(g))
+}
+
+func TestFindCycles(t *testing.T) {
+	g := New()
+	first := newChain(g, 3)
+	g.AddEdge(NewEdge(uuid.New(), first[2], first[0]))
+
+	second := newChain(g, 2)
+	g.AddEdge(NewEdge(uuid.New(), second[1], second[0]))
+	g.AddEdge(NewEdge(uuid.New(), first[2], second[0]))
+
+	acyclic := newChain(g, 3)
+	g.AddEdge(NewEdge(uuid.New(), second[1], acyclic[0]))
+
+	cycles := FindCycles(g)
+	assert.Len(t, cycles, 2)
+
+	for _, cycle := range cycles {
+		for i, n := range cycle {
+			next := cycle[(i+1)%len(cycle)]
+			assert.Contains(t, g.OutNeighbors(n.ID), next)
+		}
+	}
+
+	assert.ElementsMatch(t, first, cycles[1])
+	assert.ElementsMatch(t, second, cycles[0])
+}
+
+func TestFindCycles_None(t *testing.T) {
+	g := New()
+	newChain(g, 4)
+	assert.Empty(t, FindCycles(g))
+}
@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+)
+
+// Digest returns a hash of the nodes and edges of the graph, with their
+// payloads, labels and directions. Graphs with the same content have the same
+// digest, no matter in which order it was added. []byte and string payloads
+// are hashed as they are, encoding.BinaryMarshaler payloads as marshaled and
+// any other payload as its JSON encoding.
+func Digest[N, E any](g GraphOf[N, E]) [sha256.Size]byte {
+	h := sha256.New()
+
+	for _, n := range SortNodes(g.Nodes()) {
+		h.Write(n.ID[:])
+		writePayload(h, n.Payload)
+	}
+
+	for _, e := range SortEdges(g.Edges()) {
+		h.Write(e.ID[:])
+		h.Write(e.From.ID[:])
+		h.Write(e.To.ID[:])
+		if e.Undirected {
+			h.Write([]byte{1})
+		} else {
+			h.Write([]byte{0})
+		}
+		writeBytes(h, []byte(e.Label))
+		writePayload(h, e.Payload)
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+func writePayload(h hash.Hash, payload interface{}) {
+	switch p := payload.(type) {
+	case []byte:
+		writeBytes(h, p)
+	case string:
+		writeBytes(h, []byte(p))
+	case encoding.BinaryMarshaler:
+		b, err := p.MarshalBinary()
+		if err != nil {
+			b = []byte(fmt.Sprint(p))
+		}
+		writeBytes(h, b)
+	default:
+		b, err := json.Marshal(p)
+		if err != nil {
+			b = []byte(fmt.Sprint(p))
+		}
+		writeBytes(h, b)
+	}
+}
+
+// writeBytes writes the length of b before b, so consecutive values can not
+// be confused.
+func writeBytes(h hash.Hash, b []byte) {
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(b))))
+	h.Write(b)
+}
@@ -0,0 +1,42 @@
+package graph
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDigest(t *testing.T) {
+	node1 := NewNode(uuid.New(), []byte("a"))
+	node2 := NewNode(uuid.New(), []byte("b"))
+	edge := NewEdge(uuid.New(), node1, node2)
+
+	g1 := New()
+	g1.AddNode(node1)
+	g1.AddNode(node2)
+	g1.AddEdge(edge)
+
+	g2 := New()
+	g2.AddNode(NewNode(node2.ID, []byte("b")))
+	g2.AddNode(NewNode(node1.ID, []byte("a")))
+	g2.AddEdge(NewEdge(edge.ID, g2.GetNode(node1.ID), g2.GetNode(node2.ID)))
+	assert.Equal(t, Digest(g1), Digest(g2))
+
+	g2.GetNode(node1.ID).Payload = []byte("changed")
+	assert.NotEqual(t, Digest(g1), Digest(g2))
+}
+
+func TestDigest_TypedPayloads(t *testing.T) {
+	type payload struct {
+		Name string
+	}
+
+	digest := func(name string) [32]byte {
+		g := NewOf[payload, int]()
+		g.AddNode(NewNodeOf[payload, int](uuid.UUID{1}, payload{Name: name}))
+		return Digest(g)
+	}
+
+	assert.Equal(t, digest("a"), digest("a"))
+	assert.NotEqual(t, digest("a"), digest("b"))
+}
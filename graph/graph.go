@@ -4,92 +4,175 @@ import (
 	"github.com/google/uuid"
 )
 
-type Graph interface {
-	AddNode(*Node) bool
-	AddEdge(*Edge) bool
-	RemoveNode(*Node) bool
-	RemoveEdge(*Edge) bool
-	GetNode(uuid.UUID) *Node
-	NodeExists(*Node) bool
-	EdgeExists(*Edge) bool
-	FindPath(start *Node, end *Node) []*Node
+// GraphOf is a graph whose nodes carry payloads of type N and whose edges
+// carry payloads of type E.
+type GraphOf[N, E any] interface {
+	AddNode(*NodeOf[N, E]) bool
+	AddEdge(*EdgeOf[N, E]) bool
+	RemoveNode(*NodeOf[N, E]) bool
+	RemoveEdge(*EdgeOf[N, E]) bool
+	GetNode(uuid.UUID) *NodeOf[N, E]
+	NodeExists(*NodeOf[N, E]) bool
+	EdgeExists(*EdgeOf[N, E]) bool
+	InEdges(*NodeOf[N, E]) EdgeSetOf[N, E]
+	FindPath(start *NodeOf[N, E], end *NodeOf[N, E]) []*NodeOf[N, E]
+	Nodes() []*NodeOf[N, E]
+	Edges() []*EdgeOf[N, E]
+	OutNeighbors(uuid.UUID) []*NodeOf[N, E]
+	InNeighbors(uuid.UUID) []*NodeOf[N, E]
+	Degree(uuid.UUID) int
+	Len() int
 }
 
-type NodeSet map[uuid.UUID]*Node
-type EdgeSet map[uuid.UUID]*Edge
+type NodeSetOf[N, E any] map[uuid.UUID]*NodeOf[N, E]
+type EdgeSetOf[N, E any] map[uuid.UUID]*EdgeOf[N, E]
 
-type Node struct {
+type NodeOf[N, E any] struct {
 	ID      uuid.UUID
-	Edges   EdgeSet
-	Payload []byte
+	Edges   EdgeSetOf[N, E]
+	Payload N
 }
 
-func (n Node) GetEdges() EdgeSet {
+func (n NodeOf[N, E]) GetEdges() EdgeSetOf[N, E] {
 	return n.Edges
 }
 
-func NewNode(id uuid.UUID, payload []byte) *Node {
-	return &Node{
-		Edges:   make(EdgeSet),
+func NewNodeOf[N, E any](id uuid.UUID, payload N) *NodeOf[N, E] {
+	return &NodeOf[N, E]{
+		Edges:   make(EdgeSetOf[N, E]),
 		Payload: payload,
 		ID:      id,
 	}
 }
 
-type Edge struct {
+type EdgeOf[N, E any] struct {
 	ID   uuid.UUID
-	From *Node
-	To   *Node
+	From *NodeOf[N, E]
+	To   *NodeOf[N, E]
+	// Undirected edges can be traversed both ways, they are still stored
+	// once, under From.
+	Undirected bool
+	Label      string
+	Payload    E
 }
 
-func (e Edge) Equal(edge *Edge) bool {
-	return e.From == edge.From && e.To == edge.To
+// Equal reports whether the edges connect the same nodes with the same label,
+// regardless of their ids.
+func (e EdgeOf[N, E]) Equal(edge *EdgeOf[N, E]) bool {
+	return e.Undirected == edge.Undirected && e.Key() == edge.Key()
 }
 
-func NewEdge(id uuid.UUID, from, to *Node) *Edge {
-	return &Edge{
+func NewEdgeOf[N, E any](id uuid.UUID, from, to *NodeOf[N, E]) *EdgeOf[N, E] {
+	return &EdgeOf[N, E]{
 		From: from,
 		To:   to,
 		ID:   id,
 	}
 }
 
-type T struct {
-	List NodeSet
+func NewUndirectedEdgeOf[N, E any](id uuid.UUID, a, b *NodeOf[N, E]) *EdgeOf[N, E] {
+	edge := NewEdgeOf(id, a, b)
+	edge.Undirected = true
+	return edge
 }
 
-func New() *T {
-	return &T{
-		List: make(NodeSet),
+type TOf[N, E any] struct {
+	List NodeSetOf[N, E]
+	// In indexes the edges of List by the node they point to
+	In map[uuid.UUID]EdgeSetOf[N, E]
+
+	config
+}
+
+type config struct {
+	deterministic bool
+	undirected    bool
+	simple        bool
+}
+
+type Option func(*config)
+
+// WithDeterministicOrder makes every query and traversal of the graph visit
+// nodes and edges ordered by id, so results are the same on every run.
+func WithDeterministicOrder() Option {
+	return func(c *config) {
+		c.deterministic = true
+	}
+}
+
+// WithSimpleGraph refuses edges that are Equal to an edge already in the
+// graph. By default the graph is a multigraph and keeps parallel edges.
+func WithSimpleGraph() Option {
+	return func(c *config) {
+		c.simple = true
+	}
+}
+
+// WithUndirectedEdges makes every edge of the graph undirected, see
+// Edge.Undirected.
+func WithUndirectedEdges() Option {
+	return func(c *config) {
+		c.undirected = true
+	}
+}
+
+func NewOf[N, E any](opts ...Option) *TOf[N, E] {
+	g := &TOf[N, E]{
+		List: make(NodeSetOf[N, E]),
+		In:   make(map[uuid.UUID]EdgeSetOf[N, E]),
+	}
+
+	for _, opt := range opts {
+		opt(&g.config)
 	}
+
+	return g
+}
+
+func (g *TOf[N, E]) Deterministic() bool {
+	return g.deterministic
+}
+
+func (g *TOf[N, E]) Simple() bool {
+	return g.simple
+}
+
+// Undirected reports whether the edge can be traversed both ways in the graph.
+func (g *TOf[N, E]) Undirected(edge *EdgeOf[N, E]) bool {
+	return g.undirected || edge.Undirected
 }
 
 var _ Graph = &T{}
 
-func (g *T) FindPath(start *Node, end *Node) []*Node {
+func (g *TOf[N, E]) FindPath(start *NodeOf[N, E], end *NodeOf[N, E]) []*NodeOf[N, E] {
 
-	path := make([]*Node, 0)
+	path := make([]*NodeOf[N, E], 0)
 
 	if !g.NodeExists(start) || !g.NodeExists(end) {
 		return path
 	}
 
-	path, _ = g.findPath(start, end, path)
+	path, _ = g.findPath(start, end, path, make(map[uuid.UUID]bool))
 	return path
 }
 
-func (g *T) findPath(start *Node, end *Node, path []*Node) ([]*Node, bool) {
+func (g *TOf[N, E]) findPath(start *NodeOf[N, E], end *NodeOf[N, E], path []*NodeOf[N, E], visited map[uuid.UUID]bool) ([]*NodeOf[N, E], bool) {
 	path = append(path, start)
 
-	if start == end {
+	if start.ID == end.ID {
 		return path, true
 	}
 
-	for _, edge := range start.Edges {
-		newPath, ok := g.findPath(edge.To, end, path)
+	visited[start.ID] = true
+	for _, next := range g.OutNeighbors(start.ID) {
+		if visited[next.ID] {
+			continue
+		}
+
+		newPath, ok := g.findPath(next, end, path, visited)
 		if ok {
 			return newPath, true
 		}
 	}
-	return []*Node{}, false
+	return []*NodeOf[N, E]{}, false
 }
@@ -11,6 +11,7 @@ func TestNew(t *testing.T) {
 	assert.NotNil(t, graph)
 	assert.IsType(t, NodeSet{}, graph.List)
 	assert.Empty(t, graph.List)
+	assert.Empty(t, graph.In)
 }
 
 func TestNewNode(t *testing.T) {
@@ -165,3 +166,58 @@ func TestT_FindPathWithLoop(t *testing.T) {
 	assert.Equal(t, path[0].ID, node1.ID)
 	assert.Equal(t, path[1].ID, node4.ID)
 }
+
+func TestEdge_Equal_Label(t *testing.T) {
+	node1 := NewNode(uuid.New(), []byte{})
+	node2 := NewNode(uuid.New(), []byte{})
+
+	edge1 := NewEdge(uuid.New(), node1, node2)
+	edge2 := NewEdge(uuid.New(), NewNode(node1.ID, nil), NewNode(node2.ID, nil))
+	assert.True(t, edge1.Equal(edge2))
+
+	edge2.Label = "likes"
+	assert.False(t, edge1.Equal(edge2))
+	assert.False(t, edge1.Equal(NewEdge(uuid.New(), node2, node1)))
+	assert.True(t, NewUndirectedEdge(uuid.New(), node1, node2).Equal(NewUndirectedEdge(uuid.New(), node2, node1)))
+	assert.False(t, edge1.Equal(NewUndirectedEdge(uuid.New(), node1, node2)))
+}
+
+func TestWithSimpleGraph(t *testing.T) {
+	g := New(WithSimpleGraph())
+	assert.True(t, g.Simple())
+	assert.False(t, New().Simple())
+
+	node1 := NewNode(uuid.New(), []byte{})
+	node2 := NewNode(uuid.New(), []byte{})
+	g.AddNode(node1)
+	g.AddNode(node2)
+
+	assert.True(t, g.AddEdge(NewEdge(uuid.New(), node1, node2)))
+	assert.False(t, g.AddEdge(NewEdge(uuid.New(), node1, node2)))
+	assert.True(t, g.AddEdge(NewEdge(uuid.New(), node2, node1)))
+
+	labeled := NewEdge(uuid.New(), node1, node2)
+	labeled.Label = "likes"
+	assert.True(t, g.AddEdge(labeled))
+
+	undirected := NewUndirectedEdge(uuid.New(), node2, node1)
+	undirected.Label = "knows"
+	assert.True(t, g.AddEdge(undirected))
+
+	reverse := NewEdge(uuid.New(), node1, node2)
+	reverse.Label = "knows"
+	assert.False(t, g.AddEdge(reverse))
+	assert.Len(t, g.Edges(), 4)
+}
+
+func TestT_Multigraph(t *testing.T) {
+	g := New()
+	node1 := NewNode(uuid.New(), []byte{})
+	node2 := NewNode(uuid.New(), []byte{})
+	g.AddNode(node1)
+	g.AddNode(node2)
+
+	assert.True(t, g.AddEdge(NewEdge(uuid.New(), node1, node2)))
+	assert.True(t, g.AddEdge(NewEdge(uuid.New(), node1, node2)))
+	assert.Len(t, g.Edges(), 2)
+}
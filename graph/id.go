@@ -0,0 +1,60 @@
+package graph
+
+import (
+	"bytes"
+	"github.com/google/uuid"
+)
+
+// EdgeNamespace is the namespace of the ids derived by EdgeID.
+var EdgeNamespace = uuid.MustParse("8c7a8f4e-0f5d-4d0c-9b8e-2d1c6a3f9e70")
+
+// NodeNamespace is the root of the namespaces returned by Namespace.
+var NodeNamespace = uuid.MustParse("2f4b9c1d-6e3a-4f8b-8d2c-7a5e0b9c3d14")
+
+// Namespace derives a namespace for NodeID from a name, e.g. the name of the
+// source the nodes are imported from or the type of the entities.
+func Namespace(name string) uuid.UUID {
+	return uuid.NewSHA1(NodeNamespace, []byte(name))
+}
+
+// NodeID derives a node id (UUIDv5) from a namespace and a natural key, so
+// replicas that create a node for the same entity give it the same id and the
+// node is a single element after Merge.
+func NodeID(namespace uuid.UUID, key string) uuid.UUID {
+	return uuid.NewSHA1(namespace, []byte(key))
+}
+
+// NewKeyedNodeOf returns a node with the id derived by NodeID.
+func NewKeyedNodeOf[N, E any](namespace uuid.UUID, key string, payload N) *NodeOf[N, E] {
+	return NewNodeOf[N, E](NodeID(namespace, key), payload)
+}
+
+// NewKeyedEdgeOf returns an edge with the given label and the id derived by
+// EdgeID from its endpoints and label.
+func NewKeyedEdgeOf[N, E any](from, to *NodeOf[N, E], label string) *EdgeOf[N, E] {
+	edge := NewEdgeOf(EdgeID(from.ID, to.ID, label), from, to)
+	edge.Label = label
+	return edge
+}
+
+// EdgeID derives an edge id from the ids of its endpoints and its label, so
+// edges between the same nodes with the same label get the same id on every
+// replica.
+func EdgeID(from, to uuid.UUID, label string) uuid.UUID {
+	name := make([]byte, 0, 32+len(label))
+	name = append(name, from[:]...)
+	name = append(name, to[:]...)
+	name = append(name, label...)
+	return uuid.NewSHA1(EdgeNamespace, name)
+}
+
+// Key returns the id derived by EdgeID for the edge. The endpoints of an
+// undirected edge are ordered by id first, so the key does not depend on
+// which end is From.
+func (e EdgeOf[N, E]) Key() uuid.UUID {
+	from, to := e.From.ID, e.To.ID
+	if e.Undirected && bytes.Compare(from[:], to[:]) > 0 {
+		from, to = to, from
+	}
+	return EdgeID(from, to, e.Label)
+}
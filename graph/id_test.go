@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEdgeID(t *testing.T) {
+	from := uuid.New()
+	to := uuid.New()
+
+	assert.Equal(t, EdgeID(from, to, "likes"), EdgeID(from, to, "likes"))
+	assert.NotEqual(t, EdgeID(from, to, "likes"), EdgeID(to, from, "likes"))
+	assert.NotEqual(t, EdgeID(from, to, "likes"), EdgeID(from, to, "knows"))
+	assert.Equal(t, uuid.Version(5), EdgeID(from, to, "").Version())
+}
+
+func TestEdge_Key(t *testing.T) {
+	node1 := NewNode(uuid.New(), []byte{})
+	node2 := NewNode(uuid.New(), []byte{})
+
+	edge := NewEdge(uuid.New(), node1, node2)
+	edge.Label = "likes"
+	assert.Equal(t, EdgeID(node1.ID, node2.ID, "likes"), edge.Key())
+	assert.NotEqual(t, edge.Key(), NewEdge(uuid.New(), node2, node1).Key())
+
+	assert.Equal(t,
+		NewUndirectedEdge(uuid.New(), node1, node2).Key(),
+		NewUndirectedEdge(uuid.New(), node2, node1).Key(),
+	)
+}
+
+func TestNamespace(t *testing.T) {
+	assert.Equal(t, Namespace("users"), Namespace("users"))
+	assert.NotEqual(t, Namespace("users"), Namespace("groups"))
+}
+
+func TestNodeID(t *testing.T) {
+	users := Namespace("users")
+	groups := Namespace("groups")
+
+	assert.Equal(t, NodeID(users, "alice"), NodeID(users, "alice"))
+	assert.NotEqual(t, NodeID(users, "alice"), NodeID(users, "bob"))
+	assert.NotEqual(t, NodeID(users, "alice"), NodeID(groups, "alice"))
+	assert.Equal(t, uuid.Version(5), NodeID(users, "alice").Version())
+}
+
+func TestNewKeyedNode(t *testing.T) {
+	users := Namespace("users")
+	node := NewKeyedNode(users, "alice", []byte("hello"))
+
+	assert.Equal(t, NodeID(users, "alice"), node.ID)
+	assert.Equal(t, []byte("hello"), node.Payload)
+	assert.Empty(t, node.Edges)
+}
+
+func TestNewKeyedEdge(t *testing.T) {
+	users := Namespace("users")
+	alice := NewKeyedNode(users, "alice", []byte{})
+	bob := NewKeyedNode(users, "bob", []byte{})
+
+	edge := NewKeyedEdge(alice, bob, "follows")
+	assert.Equal(t, EdgeID(alice.ID, bob.ID, "follows"), edge.ID)
+	assert.Equal(t, edge.Key(), edge.ID)
+	assert.Equal(t, "follows", edge.Label)
+	assert.Equal(t, alice, edge.From)
+	assert.Equal(t, bob, edge.To)
+}
@@ -0,0 +1,77 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+)
+
+type jsonNode[N any] struct {
+	ID      uuid.UUID `json:"id"`
+	Payload N         `json:"payload"`
+}
+
+type jsonEdge[E any] struct {
+	ID         uuid.UUID `json:"id"`
+	From       uuid.UUID `json:"from"`
+	To         uuid.UUID `json:"to"`
+	Undirected bool      `json:"undirected,omitempty"`
+	Label      string    `json:"label,omitempty"`
+	Payload    E         `json:"payload"`
+}
+
+// MarshalJSON encodes the id and payload of the node. The edges are left out,
+// they are encoded on their own.
+func (n NodeOf[N, E]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonNode[N]{ID: n.ID, Payload: n.Payload})
+}
+
+func (n *NodeOf[N, E]) UnmarshalJSON(data []byte) error {
+	var v jsonNode[N]
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*n = *NewNodeOf[N, E](v.ID, v.Payload)
+	return nil
+}
+
+// MarshalJSON encodes the edge with the ids of its endpoints.
+func (e EdgeOf[N, E]) MarshalJSON() ([]byte, error) {
+	if e.From == nil || e.To == nil {
+		return nil, fmt.Errorf("edge %s is missing an endpoint", e.ID)
+	}
+
+	return json.Marshal(jsonEdge[E]{
+		ID:         e.ID,
+		From:       e.From.ID,
+		To:         e.To.ID,
+		Undirected: e.Undirected,
+		Label:      e.Label,
+		Payload:    e.Payload,
+	})
+}
+
+// UnmarshalJSON decodes an edge encoded by MarshalJSON. Its endpoints are
+// new nodes holding only their id, the graph resolves them by id. Edges
+// without both endpoints are rejected.
+func (e *EdgeOf[N, E]) UnmarshalJSON(data []byte) error {
+	var v jsonEdge[E]
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.From == uuid.Nil || v.To == uuid.Nil {
+		return fmt.Errorf("edge %s is missing an endpoint", v.ID)
+	}
+
+	var zero N
+	*e = EdgeOf[N, E]{
+		ID:         v.ID,
+		From:       NewNodeOf[N, E](v.From, zero),
+		To:         NewNodeOf[N, E](v.To, zero),
+		Undirected: v.Undirected,
+		Label:      v.Label,
+		Payload:    v.Payload,
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package graph
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNode_JSON(t *testing.T) {
+	node1 := NewNode(uuid.New(), []byte("hello"))
+	node2 := NewNode(uuid.New(), nil)
+	g := New()
+	g.AddNode(node1)
+	g.AddNode(node2)
+	g.AddEdge(NewEdge(uuid.New(), node1, node2))
+
+	data, err := json.Marshal(node1)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"`+node1.ID.String()+`","payload":"aGVsbG8="}`, string(data))
+
+	var decoded Node
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, node1.ID, decoded.ID)
+	assert.Equal(t, node1.Payload, decoded.Payload)
+	assert.Empty(t, decoded.Edges)
+	assert.NotNil(t, decoded.Edges)
+}
+
+func TestEdge_JSON(t *testing.T) {
+	type weight struct {
+		Value int `json:"value"`
+	}
+
+	from := NewNodeOf[string, weight](uuid.New(), "a")
+	to := NewNodeOf[string, weight](uuid.New(), "b")
+	edge := NewUndirectedEdgeOf(uuid.New(), from, to)
+	edge.Label = "knows"
+	edge.Payload = weight{Value: 3}
+
+	data, err := json.Marshal(edge)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"`+edge.ID.String()+`","from":"`+from.ID.String()+`","to":"`+to.ID.String()+
+		`","undirected":true,"label":"knows","payload":{"value":3}}`, string(data))
+
+	var decoded EdgeOf[string, weight]
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.True(t, decoded.Equal(edge))
+	assert.Equal(t, edge.ID, decoded.ID)
+	assert.Equal(t, weight{Value: 3}, decoded.Payload)
+
+	_, err = json.Marshal(NewEdge(uuid.New(), nil, nil))
+	assert.Error(t, err)
+
+	var missing Edge
+	assert.Error(t, json.Unmarshal([]byte(`{"id":"`+edge.ID.String()+`","from":"`+from.ID.String()+`"}`), &missing))
+	assert.Error(t, json.Unmarshal([]byte(`{"id":"`+edge.ID.String()+`"}`), &missing))
+}
@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"bytes"
+	"github.com/google/uuid"
+	"sort"
+)
+
+// Nodes returns the nodes of the graph, ordered by id when the graph was
+// created WithDeterministicOrder and in no particular order otherwise.
+func (g *TOf[N, E]) Nodes() []*NodeOf[N, E] {
+	nodes := make([]*NodeOf[N, E], 0, len(g.List))
+	for _, n := range g.List {
+		nodes = append(nodes, n)
+	}
+	return g.sortNodes(nodes)
+}
+
+// Edges returns the edges of the graph, ordered by id when the graph was
+// created WithDeterministicOrder and in no particular order otherwise.
+func (g *TOf[N, E]) Edges() []*EdgeOf[N, E] {
+	edges := make([]*EdgeOf[N, E], 0)
+	for _, n := range g.List {
+		for _, e := range n.Edges {
+			edges = append(edges, e)
+		}
+	}
+
+	if g.deterministic {
+		return SortEdges(edges)
+	}
+	return edges
+}
+
+// OutNeighbors returns the nodes the node with the given id has an edge to,
+// including the other end of undirected edges pointing to the node.
+func (g *TOf[N, E]) OutNeighbors(id uuid.UUID) []*NodeOf[N, E] {
+	node, ok := g.List[id]
+	if !ok {
+		return []*NodeOf[N, E]{}
+	}
+
+	ends := make([]uuid.UUID, 0, len(node.Edges))
+	for _, e := range node.Edges {
+		ends = append(ends, e.To.ID)
+	}
+	for _, e := range g.In[id] {
+		if g.Undirected(e) {
+			ends = append(ends, e.From.ID)
+		}
+	}
+	return g.neighbors(ends)
+}
+
+// InNeighbors returns the nodes that have an edge to the node with the given
+// id, including the other end of undirected edges from the node.
+func (g *TOf[N, E]) InNeighbors(id uuid.UUID) []*NodeOf[N, E] {
+	ends := make([]uuid.UUID, 0, len(g.In[id]))
+	for _, e := range g.In[id] {
+		ends = append(ends, e.From.ID)
+	}
+	if node, ok := g.List[id]; ok {
+		for _, e := range node.Edges {
+			if g.Undirected(e) {
+				ends = append(ends, e.To.ID)
+			}
+		}
+	}
+	return g.neighbors(ends)
+}
+
+// Degree returns the number of edges from and to the node with the given id,
+// a self-pointing edge is counted twice.
+func (g *TOf[N, E]) Degree(id uuid.UUID) int {
+	node, ok := g.List[id]
+	if !ok {
+		return 0
+	}
+	return len(node.Edges) + len(g.In[id])
+}
+
+// Len returns the number of nodes.
+func (g *TOf[N, E]) Len() int {
+	return len(g.List)
+}
+
+// neighbors resolves the ids through List, so that edges holding a stale copy
+// of a node still lead to the node in the graph. Every neighbor is returned
+// once.
+func (g *TOf[N, E]) neighbors(ids []uuid.UUID) []*NodeOf[N, E] {
+	nodes := make([]*NodeOf[N, E], 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+
+		if n, ok := g.List[id]; ok {
+			seen[id] = true
+			nodes = append(nodes, n)
+		}
+	}
+	return g.sortNodes(nodes)
+}
+
+func (g *TOf[N, E]) sortNodes(nodes []*NodeOf[N, E]) []*NodeOf[N, E] {
+	if g.deterministic {
+		return SortNodes(nodes)
+	}
+	return nodes
+}
+
+// SortNodes sorts the nodes by id and returns them, for a stable order.
+func SortNodes[N, E any](nodes []*NodeOf[N, E]) []*NodeOf[N, E] {
+	sort.Slice(nodes, func(i, j int) bool {
+		return bytes.Compare(nodes[i].ID[:], nodes[j].ID[:]) < 0
+	})
+	return nodes
+}
+
+// SortEdges sorts the edges by id and returns them, for a stable order.
+func SortEdges[N, E any](edges []*EdgeOf[N, E]) []*EdgeOf[N, E] {
+	sort.Slice(edges, func(i, j int) bool {
+		return bytes.Compare(edges[i].ID[:], edges[j].ID[:]) < 0
+	})
+	return edges
+}
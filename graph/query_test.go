@@ -0,0 +1,167 @@
+package graph
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newQueryGraph() (*T, []*Node, []*Edge) {
+	g := New()
+
+	nodes := []*Node{
+		NewNode(uuid.New(), []byte{}),
+		NewNode(uuid.New(), []byte{}),
+		NewNode(uuid.New(), []byte{}),
+	}
+	for _, n := range nodes {
+		g.AddNode(n)
+	}
+
+	edges := []*Edge{
+		NewEdge(uuid.New(), nodes[0], nodes[1]),
+		NewEdge(uuid.New(), nodes[0], nodes[2]),
+		NewEdge(uuid.New(), nodes[1], nodes[2]),
+		NewEdge(uuid.New(), nodes[0], nodes[1]),
+	}
+	for _, e := range edges {
+		g.AddEdge(e)
+	}
+
+	return g, nodes, edges
+}
+
+func TestT_Nodes(t *testing.T) {
+	g, nodes, _ := newQueryGraph()
+	assert.ElementsMatch(t, nodes, g.Nodes())
+	assert.Empty(t, New().Nodes())
+}
+
+func TestT_Edges(t *testing.T) {
+	g, _, edges := newQueryGraph()
+	assert.ElementsMatch(t, edges, g.Edges())
+	assert.Empty(t, New().Edges())
+}
+
+func TestT_OutNeighbors(t *testing.T) {
+	g, nodes, _ := newQueryGraph()
+	assert.ElementsMatch(t, []*Node{nodes[1], nodes[2]}, g.OutNeighbors(nodes[0].ID))
+	assert.Empty(t, g.OutNeighbors(nodes[2].ID))
+	assert.Empty(t, g.OutNeighbors(uuid.New()))
+}
+
+func TestT_OutNeighbors_ResolvesStaleNodes(t *testing.T) {
+	g := New()
+	node1 := NewNode(uuid.New(), []byte{})
+	node2 := NewNode(uuid.New(), []byte{})
+	g.AddNode(node1)
+	g.AddNode(node2)
+
+	stale := NewNode(node2.ID, []byte("stale"))
+	g.AddEdge(NewEdge(uuid.New(), node1, stale))
+
+	assert.Equal(t, []*Node{node2}, g.OutNeighbors(node1.ID))
+}
+
+func TestT_InNeighbors(t *testing.T) {
+	g, nodes, _ := newQueryGraph()
+	assert.ElementsMatch(t, []*Node{nodes[0], nodes[1]}, g.InNeighbors(nodes[2].ID))
+	assert.Equal(t, []*Node{nodes[0]}, g.InNeighbors(nodes[1].ID))
+	assert.Empty(t, g.InNeighbors(nodes[0].ID))
+	assert.Empty(t, g.InNeighbors(uuid.New()))
+}
+
+func TestT_Degree(t *testing.T) {
+	g, nodes, _ := newQueryGraph()
+	assert.Equal(t, 3, g.Degree(nodes[0].ID))
+	assert.Equal(t, 3, g.Degree(nodes[1].ID))
+	assert.Equal(t, 2, g.Degree(nodes[2].ID))
+	assert.Equal(t, 0, g.Degree(uuid.New()))
+
+	g.AddEdge(NewEdge(uuid.New(), nodes[2], nodes[2]))
+	assert.Equal(t, 4, g.Degree(nodes[2].ID))
+}
+
+func TestT_Len(t *testing.T) {
+	g, nodes, _ := newQueryGraph()
+	assert.Equal(t, 3, g.Len())
+
+	g.RemoveNode(nodes[0])
+	assert.Equal(t, 2, g.Len())
+	assert.Equal(t, 0, New().Len())
+}
+
+func TestSortNodes(t *testing.T) {
+	g, _, _ := newQueryGraph()
+
+	nodes := SortNodes(g.Nodes())
+	for i := 1; i < len(nodes); i++ {
+		assert.Less(t, nodes[i-1].ID.String(), nodes[i].ID.String())
+	}
+}
+
+func TestSortEdges(t *testing.T) {
+	g, _, _ := newQueryGraph()
+
+	edges := SortEdges(g.Edges())
+	for i := 1; i < len(edges); i++ {
+		assert.Less(t, edges[i-1].ID.String(), edges[i].ID.String())
+	}
+}
+
+func TestWithDeterministicOrder(t *testing.T) {
+	g := New(WithDeterministicOrder())
+	assert.True(t, g.Deterministic())
+	assert.False(t, New().Deterministic())
+
+	hub := NewNode(uuid.New(), []byte{})
+	g.AddNode(hub)
+	for i := 0; i < 20; i++ {
+		n := NewNode(uuid.New(), []byte{})
+		g.AddNode(n)
+		g.AddEdge(NewEdge(uuid.New(), hub, n))
+		g.AddEdge(NewEdge(uuid.New(), n, hub))
+	}
+
+	nodes := g.Nodes()
+	assert.Equal(t, SortNodes(g.Nodes()), nodes)
+
+	edges := g.Edges()
+	assert.Equal(t, SortEdges(g.Edges()), edges)
+
+	out := g.OutNeighbors(hub.ID)
+	assert.Equal(t, SortNodes(g.OutNeighbors(hub.ID)), out)
+
+	in := g.InNeighbors(hub.ID)
+	assert.Equal(t, SortNodes(g.InNeighbors(hub.ID)), in)
+}
+
+func TestWithDeterministicOrder_FindPath(t *testing.T) {
+	start := NewNode(uuid.New(), []byte{})
+	end := NewNode(uuid.New(), []byte{})
+	middle := make([]*Node, 10)
+	for i := range middle {
+		middle[i] = NewNode(uuid.New(), []byte{})
+	}
+
+	var first []*Node
+	for run := 0; run < 10; run++ {
+		g := New(WithDeterministicOrder())
+		g.AddNode(start)
+		g.AddNode(end)
+		for _, n := range middle {
+			g.AddNode(n)
+			g.AddEdge(NewEdge(uuid.New(), start, n))
+			g.AddEdge(NewEdge(uuid.New(), n, end))
+		}
+
+		path := g.FindPath(start, end)
+		assert.Len(t, path, 3)
+		assert.Equal(t, SortNodes(append([]*Node{}, middle...))[0], path[1])
+
+		if first == nil {
+			first = path
+		}
+		assert.Equal(t, first, path)
+	}
+}
@@ -0,0 +1,148 @@
+package graph
+
+import (
+	"github.com/google/uuid"
+)
+
+// Descendants returns the nodes that can be reached from the node by
+// following one or more edges, ordered by id. The node itself is only part of
+// the result if it is on a cycle.
+func Descendants[N, E any](g GraphOf[N, E], node *NodeOf[N, E]) []*NodeOf[N, E] {
+	return setToNodes(g, walk(g, node.ID, g.OutNeighbors))
+}
+
+// Ancestors returns the nodes the node can be reached from by following one
+// or more edges, ordered by id. The node itself is only part of the result if
+// it is on a cycle.
+func Ancestors[N, E any](g GraphOf[N, E], node *NodeOf[N, E]) []*NodeOf[N, E] {
+	return setToNodes(g, walk(g, node.ID, g.InNeighbors))
+}
+
+// IsReachable reports whether there is a path from a to b. Every node in the
+// graph is reachable from itself.
+func IsReachable[N, E any](g GraphOf[N, E], a, b *NodeOf[N, E]) bool {
+	if !g.NodeExists(a) || !g.NodeExists(b) {
+		return false
+	}
+	return a.ID == b.ID || walk(g, a.ID, g.OutNeighbors)[b.ID]
+}
+
+// walk returns the ids of the nodes reached from the given id through next,
+// not counting the start unless it is reached again.
+func walk[N, E any](g GraphOf[N, E], id uuid.UUID, next func(uuid.UUID) []*NodeOf[N, E]) map[uuid.UUID]bool {
+	reached := make(map[uuid.UUID]bool)
+	queue := []uuid.UUID{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, n := range next(current) {
+			if !reached[n.ID] {
+				reached[n.ID] = true
+				queue = append(queue, n.ID)
+			}
+		}
+	}
+	return reached
+}
+
+func setToNodes[N, E any](g GraphOf[N, E], set map[uuid.UUID]bool) []*NodeOf[N, E] {
+	nodes := make([]*NodeOf[N, E], 0, len(set))
+	for id := range set {
+		if n := g.GetNode(id); n != nil {
+			nodes = append(nodes, n)
+		}
+	}
+	return SortNodes(nodes)
+}
+
+// ReachabilityIndexOf caches the descendants and ancestors of the nodes of a
+// graph as they are queried. Adding an edge updates the cache, any other
+// change of the edges or removal of a node needs a call to Invalidate.
+type ReachabilityIndexOf[N, E any] struct {
+	g           GraphOf[N, E]
+	descendants map[uuid.UUID]map[uuid.UUID]bool
+	ancestors   map[uuid.UUID]map[uuid.UUID]bool
+}
+
+func NewReachabilityIndex[N, E any](g GraphOf[N, E]) *ReachabilityIndexOf[N, E] {
+	r := &ReachabilityIndexOf[N, E]{}
+	r.Reset(g)
+	return r
+}
+
+// Reset drops the cache and makes the index answer for the given graph.
+func (r *ReachabilityIndexOf[N, E]) Reset(g GraphOf[N, E]) {
+	r.g = g
+	r.Invalidate()
+}
+
+// Invalidate drops the cache.
+func (r *ReachabilityIndexOf[N, E]) Invalidate() {
+	r.descendants = make(map[uuid.UUID]map[uuid.UUID]bool)
+	r.ancestors = make(map[uuid.UUID]map[uuid.UUID]bool)
+}
+
+// AddEdge updates the cache after an edge from one node to another was added
+// to the graph.
+func (r *ReachabilityIndexOf[N, E]) AddEdge(from, to uuid.UUID) {
+	update(r.descendants, from, to, func() map[uuid.UUID]bool {
+		return walk(r.g, to, r.g.OutNeighbors)
+	})
+	update(r.ancestors, to, from, func() map[uuid.UUID]bool {
+		return walk(r.g, from, r.g.InNeighbors)
+	})
+}
+
+// update adds the nodes reached through a new edge from one node to another
+// to every cached set that reaches the first one.
+func update(cache map[uuid.UUID]map[uuid.UUID]bool, from, to uuid.UUID, beyond func() map[uuid.UUID]bool) {
+	var added map[uuid.UUID]bool
+	for id, reached := range cache {
+		if id != from && !reached[from] {
+			continue
+		}
+
+		if added == nil {
+			added = beyond()
+		}
+
+		reached[to] = true
+		for k := range added {
+			reached[k] = true
+		}
+	}
+}
+
+func (r *ReachabilityIndexOf[N, E]) Descendants(node *NodeOf[N, E]) []*NodeOf[N, E] {
+	return setToNodes(r.g, r.descendantsOf(node.ID))
+}
+
+func (r *ReachabilityIndexOf[N, E]) Ancestors(node *NodeOf[N, E]) []*NodeOf[N, E] {
+	return setToNodes(r.g, r.ancestorsOf(node.ID))
+}
+
+func (r *ReachabilityIndexOf[N, E]) IsReachable(a, b *NodeOf[N, E]) bool {
+	if !r.g.NodeExists(a) || !r.g.NodeExists(b) {
+		return false
+	}
+	return a.ID == b.ID || r.descendantsOf(a.ID)[b.ID]
+}
+
+func (r *ReachabilityIndexOf[N, E]) descendantsOf(id uuid.UUID) map[uuid.UUID]bool {
+	reached, ok := r.descendants[id]
+	if !ok {
+		reached = walk(r.g, id, r.g.OutNeighbors)
+		r.descendants[id] = reached
+	}
+	return reached
+}
+
+func (r *ReachabilityIndexOf[N, E]) ancestorsOf(id uuid.UUID) map[uuid.UUID]bool {
+	reached, ok := r.ancestors[id]
+	if !ok {
+		reached = walk(r.g, id, r.g.InNeighbors)
+		r.ancestors[id] = reached
+	}
+	return reached
+}
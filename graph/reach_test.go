@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDescendants(t *testing.T) {
+	g := New()
+	nodes := newChain(g, 4)
+
+	assert.Equal(t, SortNodes(append([]*Node{}, nodes[1:]...)), Descendants(g, nodes[0]))
+	assert.Equal(t, []*Node{nodes[3]}, Descendants(g, nodes[2]))
+	assert.Empty(t, Descendants(g, nodes[3]))
+	assert.Empty(t, Descendants(g, NewNode(uuid.New(), []byte{})))
+
+	g.AddEdge(NewEdge(uuid.New(), nodes[3], nodes[2]))
+	assert.Equal(t, SortNodes([]*Node{nodes[2], nodes[3]}), Descendants(g, nodes[2]))
+}
+
+func TestAncestors(t *testing.T) {
+	g := New()
+	nodes := newChain(g, 4)
+
+	assert.Equal(t, SortNodes(append([]*Node{}, nodes[:3]...)), Ancestors(g, nodes[3]))
+	assert.Empty(t, Ancestors(g, nodes[0]))
+}
+
+func TestIsReachable(t *testing.T) {
+	g := New()
+	nodes := newChain(g, 3)
+	other := NewNode(uuid.New(), []byte{})
+	g.AddNode(other)
+
+	assert.True(t, IsReachable(g, nodes[0], nodes[2]))
+	assert.False(t, IsReachable(g, nodes[2], nodes[0]))
+	assert.True(t, IsReachable(g, nodes[1], nodes[1]))
+	assert.False(t, IsReachable(g, nodes[0], other))
+	assert.False(t, IsReachable(g, nodes[0], NewNode(uuid.New(), []byte{})))
+}
+
+func TestReachabilityIndex(t *testing.T) {
+	g := New()
+	nodes := newChain(g, 3)
+	r := NewReachabilityIndex(g)
+
+	assert.True(t, r.IsReachable(nodes[0], nodes[2]))
+	assert.False(t, r.IsReachable(nodes[2], nodes[0]))
+	assert.Equal(t, Descendants(g, nodes[0]), r.Descendants(nodes[0]))
+	assert.Equal(t, Ancestors(g, nodes[2]), r.Ancestors(nodes[2]))
+	assert.False(t, r.IsReachable(nodes[0], NewNode(uuid.New(), []byte{})))
+}
+
+func TestReachabilityIndex_AddEdge(t *testing.T) {
+	g := New()
+	first := newChain(g, 3)
+	second := newChain(g, 3)
+	r := NewReachabilityIndex(g)
+
+	assert.False(t, r.IsReachable(first[0], second[2]))
+	assert.Empty(t, r.Ancestors(second[0]))
+
+	g.AddEdge(NewEdge(uuid.New(), first[2], second[0]))
+	r.AddEdge(first[2].ID, second[0].ID)
+
+	assert.True(t, r.IsReachable(first[0], second[2]))
+	assert.Equal(t, Descendants(g, first[0]), r.Descendants(first[0]))
+	assert.Equal(t, Ancestors(g, second[0]), r.Ancestors(second[0]))
+	assert.Equal(t, Ancestors(g, second[2]), r.Ancestors(second[2]))
+
+	g.AddEdge(NewEdge(uuid.New(), second[2], first[0]))
+	r.AddEdge(second[2].ID, first[0].ID)
+	for _, n := range append(first, second...) {
+		assert.Equal(t, Descendants(g, n), r.Descendants(n))
+		assert.Equal(t, Ancestors(g, n), r.Ancestors(n))
+	}
+}
+
+func TestReachabilityIndex_Invalidate(t *testing.T) {
+	g := New()
+	nodes := newChain(g, 3)
+	r := NewReachabilityIndex(g)
+	assert.True(t, r.IsReachable(nodes[0], nodes[2]))
+
+	for _, e := range g.List[nodes[1].ID].Edges {
+		g.RemoveEdge(e)
+	}
+	assert.True(t, r.IsReachable(nodes[0], nodes[2]))
+
+	r.Invalidate()
+	assert.False(t, r.IsReachable(nodes[0], nodes[2]))
+
+	other := New()
+	r.Reset(other)
+	assert.False(t, r.IsReachable(nodes[0], nodes[1]))
+}
@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewUndirectedEdge(t *testing.T) {
+	node1 := NewNode(uuid.New(), []byte{})
+	node2 := NewNode(uuid.New(), []byte{})
+
+	edge := NewUndirectedEdge(uuid.New(), node1, node2)
+	assert.True(t, edge.Undirected)
+	assert.False(t, NewEdge(uuid.New(), node1, node2).Undirected)
+}
+
+func TestT_UndirectedEdge(t *testing.T) {
+	g := New()
+	node1 := NewNode(uuid.New(), []byte{})
+	node2 := NewNode(uuid.New(), []byte{})
+	node3 := NewNode(uuid.New(), []byte{})
+	g.AddNode(node1)
+	g.AddNode(node2)
+	g.AddNode(node3)
+
+	edge := NewUndirectedEdge(uuid.New(), node1, node2)
+	g.AddEdge(edge)
+	g.AddEdge(NewEdge(uuid.New(), node2, node3))
+
+	assert.True(t, g.Undirected(edge))
+	assert.Len(t, g.Edges(), 2)
+	assert.Equal(t, []*Node{node2}, g.OutNeighbors(node1.ID))
+	assert.ElementsMatch(t, []*Node{node1, node3}, g.OutNeighbors(node2.ID))
+	assert.Equal(t, []*Node{node1}, g.InNeighbors(node2.ID))
+	assert.Equal(t, []*Node{node2}, g.InNeighbors(node1.ID))
+	assert.Equal(t, 1, g.Degree(node1.ID))
+	assert.Equal(t, 2, g.Degree(node2.ID))
+
+	path := g.FindPath(node3, node1)
+	assert.Empty(t, path)
+
+	path = g.FindPath(node2, node1)
+	assert.Equal(t, []*Node{node2, node1}, path)
+	assert.True(t, IsReachable(g, node3, node3))
+	assert.True(t, IsReachable(g, node2, node1))
+	assert.True(t, HasCycle(g))
+
+	assert.True(t, g.RemoveNode(node2))
+	assert.Empty(t, g.OutNeighbors(node1.ID))
+	assert.Empty(t, g.InNeighbors(node1.ID))
+}
+
+func TestWithUndirectedEdges(t *testing.T) {
+	g := New(WithUndirectedEdges())
+	nodes := newChain(g, 3)
+
+	assert.True(t, g.Undirected(g.Edges()[0]))
+	assert.Equal(t, []*Node{nodes[0], nodes[1], nodes[2]}, g.FindPath(nodes[0], nodes[2]))
+	assert.Equal(t, []*Node{nodes[2], nodes[1], nodes[0]}, g.FindPath(nodes[2], nodes[0]))
+	assert.Equal(t, 1, WeaklyConnectedComponents(g).Len())
+	assert.Equal(t, 1, StronglyConnectedComponents(g).Len())
+}
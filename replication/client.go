@@ -0,0 +1,111 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/tauki/crdt"
+	"github.com/tauki/crdt/twoPSet"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client keeps an ElementGraph in sync with peers served by a Handler. Every
+// sync pulls the operations the peer has that differ from the local ones and
+// pushes the local ones back, descending into the Merkle trees of both first
+// so only the differing parts of the state are transferred.
+type Client[N, E any] struct {
+	g     *crdt.ElementGraphOf[N, E]
+	peers []string
+	config
+}
+
+// NewClient returns a client syncing with the peers at the given base URLs,
+// e.g. "http://10.0.0.2:8080".
+func NewClient[N, E any](g *crdt.ElementGraphOf[N, E], peers []string, opts ...Option) *Client[N, E] {
+	return &Client[N, E]{g: g, peers: peers, config: newConfig(opts)}
+}
+
+// Run syncs with every peer at the configured interval until the context is
+// done. Failures are passed to the error handler, see WithErrorHandler.
+func (c *Client[N, E]) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		for _, peer := range c.peers {
+			if _, err := c.SyncPeer(ctx, peer); err != nil {
+				c.onError(peer, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncPeer syncs once with the peer at the given base URL, see Exchange.
+func (c *Client[N, E]) SyncPeer(ctx context.Context, peer string) (Summary, error) {
+	peer = strings.TrimSuffix(peer, "/")
+	return Exchange(c.g, c.lock, Remote[N, E]{
+		Version: func() (Version, error) {
+			var v Version
+			err := c.do(ctx, http.MethodGet, peer+SummaryPath, nil, &v)
+			return v, err
+		},
+		Probe: func(r HashRequest) ([]twoPSet.Digest, error) {
+			var hashes []twoPSet.Digest
+			err := c.do(ctx, http.MethodPost, peer+HashesPath, r, &hashes)
+			return hashes, err
+		},
+		Pull: func(leaves Leaves) (State[N, E], error) {
+			var in State[N, E]
+			err := c.do(ctx, http.MethodPost, peer+DeltaPath, leaves, &in)
+			return in, err
+		},
+		Push: func(out State[N, E], _ twoPSet.Digest) (twoPSet.Digest, error) {
+			var end done
+			err := c.do(ctx, http.MethodPost, peer+StatePath, out, &end)
+			return end.Digest, err
+		},
+	})
+}
+
+// do sends the request, with in encoded as JSON if it is not nil, and decodes
+// the response into out.
+func (c *Client[N, E]) do(ctx context.Context, method, url string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,86 @@
+package replication
+
+import (
+	"github.com/tauki/crdt"
+	"github.com/tauki/crdt/twoPSet"
+	"sync"
+)
+
+// Remote is the other side of Exchange, each function sends one request of
+// the exchange to the peer and returns its answer.
+type Remote[N, E any] struct {
+	// Version returns the Version of the peer.
+	Version func() (Version, error)
+	// Probe returns the hashes of the Merkle tree nodes of the peer, see Diff.
+	Probe Probe
+	// Pull returns the state of the given leaves of the peer.
+	Pull func(Leaves) (State[N, E], error)
+	// Push merges the state into the peer and returns the digest of the peer
+	// afterwards. The digest of the local replica is passed along.
+	Push func(State[N, E], twoPSet.Digest) (twoPSet.Digest, error)
+}
+
+// Exchange syncs the ElementGraph with a peer through remote: it gets the
+// Version of the peer, descends into the Merkle trees where they differ,
+// pulls the operations of the leaves that differ and pushes its own
+// operations of those leaves back. The lock is held while the ElementGraph is
+// read or changed, but not while the peer is waited for.
+func Exchange[N, E any](g *crdt.ElementGraphOf[N, E], lock sync.Locker, remote Remote[N, E]) (Summary, error) {
+	result := Summary{}
+
+	version, err := remote.Version()
+	if err != nil {
+		return result, err
+	}
+
+	lock.Lock()
+	trees := newTrees(g)
+	local := trees.version(g.ReplicaID(), g.Digest())
+	lock.Unlock()
+
+	if err := local.Compatible(version); err != nil {
+		return result, err
+	}
+	result.Peer = version.Replica
+	result.Digest = version.Digest
+
+	if version.Digest == local.Digest {
+		result.Converged = true
+		return result, nil
+	}
+
+	// the peer may change while its trees are probed, the leaves found are
+	// still the ones to exchange, anything missed is found by the next sync
+	leaves, err := trees.diff(version, remote.Probe)
+	if err != nil {
+		return result, err
+	}
+	result.Leaves = leaves.Len()
+
+	in, err := remote.Pull(leaves)
+	if err != nil {
+		return result, err
+	}
+	result.ReceivedNodes, result.ReceivedEdges = in.Nodes.Len(), in.Edges.Len()
+
+	// the local operations are taken before the received ones are merged,
+	// so they are not sent back
+	lock.Lock()
+	out := Delta(g, leaves)
+	err = Apply(g, in)
+	digest := g.Digest()
+	lock.Unlock()
+	if err != nil {
+		return result, err
+	}
+
+	end, err := remote.Push(out, digest)
+	if err != nil {
+		return result, err
+	}
+	result.SentNodes, result.SentEdges = out.Nodes.Len(), out.Edges.Len()
+	result.Digest = end
+	result.Converged = end == digest
+
+	return result, nil
+}
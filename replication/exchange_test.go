@@ -0,0 +1,86 @@
+package replication
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/tauki/crdt"
+	"github.com/tauki/crdt/graph"
+	"github.com/tauki/crdt/twoPSet"
+	"sync"
+	"testing"
+)
+
+// local serves the requests of Exchange from an ElementGraph in memory.
+func local(g *crdt.ElementGraph, requests *[]string) Remote[[]byte, []byte] {
+	return Remote[[]byte, []byte]{
+		Version: func() (Version, error) {
+			*requests = append(*requests, "version")
+			return NewVersion(g), nil
+		},
+		Probe: func(r HashRequest) ([]twoPSet.Digest, error) {
+			*requests = append(*requests, "hashes")
+			return Hashes(g, r)
+		},
+		Pull: func(leaves Leaves) (State[[]byte, []byte], error) {
+			*requests = append(*requests, "pull")
+			return Delta(g, leaves), nil
+		},
+		Push: func(out State[[]byte, []byte], _ twoPSet.Digest) (twoPSet.Digest, error) {
+			*requests = append(*requests, "push")
+			err := Apply(g, out)
+			return g.Digest(), err
+		},
+	}
+}
+
+func TestExchange(t *testing.T) {
+	a := crdt.NewElementGraph()
+	b := crdt.NewElementGraph()
+	a.AddNode(graph.NewNode(uuid.New(), []byte("a")))
+	b.AddNode(graph.NewNode(uuid.New(), []byte("b")))
+
+	requests := make([]string, 0)
+	summary, err := Exchange(a, &sync.Mutex{}, local(b, &requests))
+	assert.NoError(t, err)
+	assert.True(t, summary.Converged)
+	assert.Equal(t, b.ReplicaID(), summary.Peer)
+	assert.Equal(t, b.Digest(), summary.Digest)
+	assert.Equal(t, 1, summary.SentNodes)
+	assert.Equal(t, 1, summary.ReceivedNodes)
+	assert.Equal(t, "version", requests[0])
+	assert.Equal(t, []string{"pull", "push"}, requests[len(requests)-2:])
+	assert.Equal(t, a.Digest(), b.Digest())
+
+	requests = requests[:0]
+	summary, err = Exchange(a, &sync.Mutex{}, local(b, &requests))
+	assert.NoError(t, err)
+	assert.True(t, summary.Converged)
+	assert.Equal(t, []string{"version"}, requests)
+}
+
+func TestExchange_Errors(t *testing.T) {
+	a := crdt.NewElementGraph()
+	a.AddNode(graph.NewNode(uuid.New(), nil))
+	b := crdt.NewElementGraph()
+	before := a.Digest()
+
+	requests := make([]string, 0)
+	remote := local(b, &requests)
+	failed := errors.New("failed")
+	remote.Pull = func(Leaves) (State[[]byte, []byte], error) {
+		return State[[]byte, []byte]{}, failed
+	}
+	_, err := Exchange(a, &sync.Mutex{}, remote)
+	assert.ErrorIs(t, err, failed)
+	assert.Equal(t, before, a.Digest())
+
+	remote = local(b, &requests)
+	remote.Version = func() (Version, error) {
+		v := NewVersion(b)
+		v.Protocol++
+		return v, nil
+	}
+	_, err = Exchange(a, &sync.Mutex{}, remote)
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+}
@@ -0,0 +1,196 @@
+package replication
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/tauki/crdt"
+	"github.com/tauki/crdt/twoPSet"
+	"net/http"
+)
+
+// The paths served by Handler.
+const (
+	SummaryPath = "/summary"
+	HashesPath  = "/hashes"
+	StatePath   = "/state"
+	DeltaPath   = "/delta"
+)
+
+// Leaves names the Merkle tree leaves of the node and edge sets whose
+// operations are requested from DeltaPath.
+type Leaves struct {
+	Nodes []int `json:"nodes"`
+	Edges []int `json:"edges"`
+}
+
+// Len returns the number of leaves.
+func (l Leaves) Len() int {
+	return len(l.Nodes) + len(l.Edges)
+}
+
+// Handler serves the state of an ElementGraph to replicas that pull from it,
+// and merges the state they push:
+//
+//	GET  /summary  the Version of the state, its digest and Merkle tree roots
+//	POST /hashes   the hashes of the Merkle tree nodes in the HashRequest
+//	GET  /state    the full state
+//	POST /delta    the state of the Leaves in the request body
+//	POST /state    merges the State in the request body, returns the digest
+//
+// Pushed state that is malformed is refused with 422 Unprocessable Entity,
+// request bodies larger than the configured size with 413 Request Entity Too
+// Large.
+type Handler[N, E any] struct {
+	g *crdt.ElementGraphOf[N, E]
+	config
+	mux *http.ServeMux
+}
+
+func NewHandler[N, E any](g *crdt.ElementGraphOf[N, E], opts ...Option) *Handler[N, E] {
+	h := &Handler[N, E]{g: g, config: newConfig(opts), mux: http.NewServeMux()}
+	h.mux.HandleFunc(SummaryPath, h.summary)
+	h.mux.HandleFunc(HashesPath, h.hashes)
+	h.mux.HandleFunc(StatePath, h.state)
+	h.mux.HandleFunc(DeltaPath, h.delta)
+	return h
+}
+
+func (h *Handler[N, E]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler[N, E]) summary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	h.lock.Lock()
+	s := NewVersion(h.g)
+	h.lock.Unlock()
+	writeJSON(w, http.StatusOK, s)
+}
+
+func (h *Handler[N, E]) hashes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var req HashRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	h.lock.Lock()
+	hashes, err := Hashes(h.g, req)
+	h.lock.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, hashes)
+}
+
+func (h *Handler[N, E]) state(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		// the state is encoded before unlocking, it points into the sets,
+		// and written after, so a slow client does not hold the lock
+		h.lock.Lock()
+		data, err := json.Marshal(Export(h.g))
+		h.lock.Unlock()
+		writeEncoded(w, data, err)
+	case http.MethodPost:
+		var in State[N, E]
+		if !h.decode(w, r, &in) {
+			return
+		}
+
+		h.lock.Lock()
+		err := Apply(h.g, in)
+		digest := h.g.Digest()
+		h.lock.Unlock()
+
+		if errors.Is(err, crdt.ErrInvalidState) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, done{Digest: digest})
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (h *Handler[N, E]) delta(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var leaves Leaves
+	if !h.decode(w, r, &leaves) {
+		return
+	}
+
+	h.lock.Lock()
+	data, err := json.Marshal(Delta(h.g, leaves))
+	h.lock.Unlock()
+	writeEncoded(w, data, err)
+}
+
+// Delta returns the state of the given leaves of the sets of the
+// ElementGraph.
+func Delta[N, E any](g *crdt.ElementGraphOf[N, E], leaves Leaves) State[N, E] {
+	return State[N, E]{
+		Nodes: newSet(twoPSet.Delta(g.NodeSet, twoPSet.DefaultTreeDepth, leaves.Nodes)),
+		Edges: newSet(twoPSet.Delta(g.EdgeSet, twoPSet.DefaultTreeDepth, leaves.Edges)),
+	}
+}
+
+// decode reads the request body into v, at most the configured size of it.
+// It writes the error response and returns false if the body can not be read.
+func (h *Handler[N, E]) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.maxBody)).Decode(v)
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return false
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeEncoded writes a response encoded with json.Marshal, or the error of
+// the encoding.
+func writeEncoded(w http.ResponseWriter, data []byte, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(data, '\n'))
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	for _, m := range allowed {
+		w.Header().Add("Allow", m)
+	}
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,208 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/tauki/crdt"
+	"github.com/tauki/crdt/graph"
+	"github.com/tauki/crdt/twoPSet"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newPeer(t *testing.T, payload string) (*crdt.ElementGraph, *httptest.Server) {
+	g := crdt.NewElementGraph()
+	node1 := graph.NewNode(uuid.New(), []byte(payload))
+	node2 := graph.NewNode(uuid.New(), []byte(payload))
+	g.AddNode(node1)
+	g.AddNode(node2)
+	g.AddEdge(graph.NewEdge(uuid.New(), node1, node2))
+
+	server := httptest.NewServer(NewHandler(g))
+	t.Cleanup(server.Close)
+	return g, server
+}
+
+func TestClient_SyncPeer(t *testing.T) {
+	remote, server := newPeer(t, "remote")
+	local := crdt.NewElementGraph()
+	local.AddNode(graph.NewNode(uuid.New(), []byte("local")))
+
+	client := NewClient(local, []string{server.URL})
+	summary, err := client.SyncPeer(context.Background(), server.URL)
+	assert.NoError(t, err)
+	assert.True(t, summary.Converged)
+	assert.Equal(t, remote.ReplicaID(), summary.Peer)
+	assert.Equal(t, 2, summary.ReceivedNodes)
+	assert.Equal(t, 1, summary.ReceivedEdges)
+	assert.Equal(t, 1, summary.SentNodes)
+
+	summary, err = client.SyncPeer(context.Background(), server.URL+"/")
+	assert.NoError(t, err)
+	assert.True(t, summary.Converged)
+	assert.Zero(t, summary.Leaves)
+
+	server.Close()
+	assert.Equal(t, remote.Digest(), local.Digest())
+	assert.Equal(t, remote.GraphDigest(), local.GraphDigest())
+	assert.Equal(t, 3, local.Graph.Len())
+}
+
+func TestHandler_State(t *testing.T) {
+	remote, server := newPeer(t, "remote")
+
+	resp, err := http.Get(server.URL + StatePath)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var state State[[]byte, []byte]
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&state))
+	assert.Equal(t, 2, state.Nodes.Len())
+	assert.Equal(t, 1, state.Edges.Len())
+
+	g := crdt.NewElementGraph()
+	assert.NoError(t, Apply(g, state))
+	assert.Equal(t, remote.Digest(), g.Digest())
+}
+
+func TestHandler_Errors(t *testing.T) {
+	remote, server := newPeer(t, "remote")
+	before := remote.Digest()
+
+	node := graph.NewNode(uuid.New(), nil)
+	malformed, _ := json.Marshal(State[[]byte, []byte]{
+		Edges: Set[*graph.Edge]{Add: twoPSet.SetOf[*graph.Edge]{
+			uuid.New(): {Payload: graph.NewEdge(uuid.New(), node, node), Timestamp: time.Now()},
+		}},
+	})
+
+	id := uuid.New().String()
+	noEndpoints := `{"nodes":{},"edges":{"add":{"` + id + `":{"Payload":{"id":"` + id + `"}}}}}`
+
+	tests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{http.MethodPost, StatePath, string(malformed), http.StatusUnprocessableEntity},
+		{http.MethodPost, StatePath, noEndpoints, http.StatusBadRequest},
+		{http.MethodPost, StatePath, "{", http.StatusBadRequest},
+		{http.MethodPost, DeltaPath, "[", http.StatusBadRequest},
+		{http.MethodPost, HashesPath, `{"tree":"nodes","level":3,"indices":[0]}`, http.StatusBadRequest},
+		{http.MethodPost, HashesPath, `{"tree":"roots","level":1,"indices":[0]}`, http.StatusBadRequest},
+		{http.MethodGet, HashesPath, "", http.StatusMethodNotAllowed},
+		{http.MethodDelete, StatePath, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, SummaryPath, "", http.StatusMethodNotAllowed},
+		{http.MethodGet, DeltaPath, "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, tt.method+" "+tt.path)
+	}
+
+	server.Close()
+	assert.Equal(t, before, remote.Digest())
+}
+
+func TestHandler_MaxBodySize(t *testing.T) {
+	g := crdt.NewElementGraph()
+	server := httptest.NewServer(NewHandler(g, WithMaxBodySize(64)))
+	defer server.Close()
+
+	body := `{"nodes":[` + strings.Repeat("0,", 64) + `0],"edges":[]}`
+	for _, path := range []string{StatePath, DeltaPath, HashesPath} {
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode, path)
+	}
+
+	resp, err := http.Post(server.URL+DeltaPath, "application/json", strings.NewReader(`{"nodes":[0],"edges":[]}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// unlockedWriter records whether the lock was free while the body was
+// written.
+type unlockedWriter struct {
+	*httptest.ResponseRecorder
+	lock     *sync.Mutex
+	unlocked bool
+}
+
+func (w *unlockedWriter) Write(data []byte) (int, error) {
+	if w.lock.TryLock() {
+		w.unlocked = true
+		w.lock.Unlock()
+	}
+	return w.ResponseRecorder.Write(data)
+}
+
+func TestHandler_WritesUnlocked(t *testing.T) {
+	g, _ := newPeer(t, "remote")
+	lock := &sync.Mutex{}
+	h := NewHandler(g, WithLock(lock))
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodGet, StatePath, nil),
+		httptest.NewRequest(http.MethodPost, DeltaPath, strings.NewReader(`{"nodes":[0],"edges":[0]}`)),
+	}
+	for _, r := range requests {
+		w := &unlockedWriter{ResponseRecorder: httptest.NewRecorder(), lock: lock}
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code, r.URL.Path)
+		assert.True(t, w.unlocked, r.URL.Path)
+
+		var state State[[]byte, []byte]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	}
+}
+
+func TestWithInterval(t *testing.T) {
+	c := NewClient(crdt.NewElementGraph(), nil, WithInterval(0))
+	assert.Equal(t, 10*time.Second, c.interval)
+
+	c = NewClient(crdt.NewElementGraph(), nil, WithInterval(-time.Second), WithInterval(time.Millisecond))
+	assert.Equal(t, time.Millisecond, c.interval)
+}
+
+func TestClient_Run(t *testing.T) {
+	remote1, server1 := newPeer(t, "one")
+	remote2, server2 := newPeer(t, "two")
+	local := crdt.NewElementGraph()
+
+	var mu sync.Mutex
+	failed := make([]string, 0)
+	client := NewClient(local, []string{server1.URL, server2.URL, "http://127.0.0.1:0"},
+		WithInterval(time.Millisecond),
+		WithErrorHandler(func(peer string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, peer)
+		}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, client.Run(ctx), context.DeadlineExceeded)
+
+	server1.Close()
+	server2.Close()
+	assert.Equal(t, 4, local.Graph.Len())
+	assert.Equal(t, local.Digest(), remote1.Digest())
+	assert.Equal(t, local.Digest(), remote2.Digest())
+	mu.Lock()
+	assert.Contains(t, failed, "http://127.0.0.1:0")
+	mu.Unlock()
+}
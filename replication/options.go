@@ -0,0 +1,81 @@
+package replication
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+type config struct {
+	lock     sync.Locker
+	client   *http.Client
+	interval time.Duration
+	onError  func(peer string, err error)
+	maxBody  int64
+}
+
+// DefaultMaxBodySize is the largest request body the Handler reads unless set
+// WithMaxBodySize.
+const DefaultMaxBodySize = 32 << 20
+
+type Option func(*config)
+
+// WithLock sets the lock held while the ElementGraph is read or changed. The
+// application must hold the same lock when it uses the ElementGraph. A lock
+// private to the Handler or Client is used otherwise.
+func WithLock(lock sync.Locker) Option {
+	return func(c *config) {
+		c.lock = lock
+	}
+}
+
+// WithHTTPClient sets the client used to reach the peers, http.DefaultClient
+// is used otherwise.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// WithInterval sets how often Client.Run syncs with the peers, every 10
+// seconds by default. Intervals that are not positive are ignored.
+func WithInterval(interval time.Duration) Option {
+	return func(c *config) {
+		if interval > 0 {
+			c.interval = interval
+		}
+	}
+}
+
+// WithMaxBodySize sets the largest request body the Handler reads, larger
+// bodies are refused with 413 Request Entity Too Large. Sizes that are not
+// positive are ignored.
+func WithMaxBodySize(size int64) Option {
+	return func(c *config) {
+		if size > 0 {
+			c.maxBody = size
+		}
+	}
+}
+
+// WithErrorHandler sets a function called by Client.Run with every failed
+// sync, failures are ignored otherwise.
+func WithErrorHandler(onError func(peer string, err error)) Option {
+	return func(c *config) {
+		c.onError = onError
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		lock:     &sync.Mutex{},
+		client:   http.DefaultClient,
+		interval: 10 * time.Second,
+		onError:  func(string, error) {},
+		maxBody:  DefaultMaxBodySize,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
@@ -0,0 +1,332 @@
+// Package replication keeps ElementGraph replicas in sync over a connection
+// or over HTTP.
+package replication
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/tauki/crdt"
+	"github.com/tauki/crdt/graph"
+	"github.com/tauki/crdt/twoPSet"
+	"io"
+)
+
+// ProtocolVersion is the version of the messages exchanged by Sync.
+const ProtocolVersion = 2
+
+// ErrVersionMismatch is returned when the other side speaks another version
+// of the protocol.
+var ErrVersionMismatch = errors.New("protocol version mismatch")
+
+// Summary describes what a sync session moved, from the point of view of the
+// local replica.
+type Summary struct {
+	// Peer is the replica id of the other side.
+	Peer uuid.UUID
+	// Leaves is the number of Merkle tree leaves, of the node and edge sets,
+	// whose operations were exchanged.
+	Leaves int
+	// SentNodes, SentEdges, ReceivedNodes and ReceivedEdges count the
+	// operations, adds and removes, that were sent and received.
+	SentNodes     int
+	SentEdges     int
+	ReceivedNodes int
+	ReceivedEdges int
+	// Digest is the digest of the peer at the end of the session.
+	Digest twoPSet.Digest
+	// Converged reports whether both replicas had the same digest at the end
+	// of the session.
+	Converged bool
+}
+
+// Version summarizes the state of a replica: its digest and the roots of the
+// Merkle trees of its node and edge sets. It is the first message of a
+// session. Replicas whose roots differ descend into their trees with Diff.
+type Version struct {
+	Protocol int            `json:"version"`
+	Replica  uuid.UUID      `json:"replica"`
+	Depth    int            `json:"depth"`
+	Digest   twoPSet.Digest `json:"digest"`
+	Nodes    twoPSet.Digest `json:"nodes"`
+	Edges    twoPSet.Digest `json:"edges"`
+}
+
+// The sets a HashRequest can ask for.
+const (
+	NodeTree = "nodes"
+	EdgeTree = "edges"
+)
+
+// HashRequest asks a replica for the hashes of the nodes with the given
+// indices at the given level of the Merkle tree of its node or edge set, see
+// twoPSet.Tree.Hashes.
+type HashRequest struct {
+	Tree    string `json:"tree"`
+	Level   int    `json:"level"`
+	Indices []int  `json:"indices"`
+}
+
+// Probe sends a HashRequest to the other replica and returns its answer.
+type Probe func(HashRequest) ([]twoPSet.Digest, error)
+
+// NewVersion returns the version of the state of the ElementGraph.
+func NewVersion[N, E any](g *crdt.ElementGraphOf[N, E]) Version {
+	return newTrees(g).version(g.ReplicaID(), g.Digest())
+}
+
+// Compatible checks that the remote version can be compared with v.
+func (v Version) Compatible(remote Version) error {
+	if remote.Protocol != v.Protocol {
+		return fmt.Errorf("%w: got %d, want %d", ErrVersionMismatch, remote.Protocol, v.Protocol)
+	}
+	if remote.Depth != v.Depth {
+		return fmt.Errorf("version of %s does not match the tree depth %d", remote.Replica, v.Depth)
+	}
+	return nil
+}
+
+// Diff returns the leaves where the ElementGraph differs from the remote
+// Version, which must be Compatible. The roots come from the version, below
+// them the remote trees are asked through the probe, level by level, for the
+// children of the nodes that differ only, so replicas that are mostly in sync
+// exchange few hashes.
+func Diff[N, E any](g *crdt.ElementGraphOf[N, E], remote Version, probe Probe) (Leaves, error) {
+	return newTrees(g).diff(remote, probe)
+}
+
+// Hashes answers a HashRequest of another replica.
+func Hashes[N, E any](g *crdt.ElementGraphOf[N, E], r HashRequest) ([]twoPSet.Digest, error) {
+	return newTrees(g).hashes(r)
+}
+
+// trees are the Merkle trees of the sets of an ElementGraph.
+type trees struct {
+	nodes, edges *twoPSet.Tree
+}
+
+func newTrees[N, E any](g *crdt.ElementGraphOf[N, E]) trees {
+	return trees{
+		nodes: twoPSet.NewTree(g.NodeSet, twoPSet.DefaultTreeDepth),
+		edges: twoPSet.NewTree(g.EdgeSet, twoPSet.DefaultTreeDepth),
+	}
+}
+
+func (t trees) version(replica uuid.UUID, digest twoPSet.Digest) Version {
+	return Version{
+		Protocol: ProtocolVersion,
+		Replica:  replica,
+		Depth:    t.nodes.Depth,
+		Digest:   digest,
+		Nodes:    t.nodes.Root(),
+		Edges:    t.edges.Root(),
+	}
+}
+
+func (t trees) tree(name string) (*twoPSet.Tree, error) {
+	switch name {
+	case NodeTree:
+		return t.nodes, nil
+	case EdgeTree:
+		return t.edges, nil
+	}
+	return nil, fmt.Errorf("unknown tree %q", name)
+}
+
+func (t trees) hashes(r HashRequest) ([]twoPSet.Digest, error) {
+	tree, err := t.tree(r.Tree)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Hashes(r.Level, r.Indices)
+}
+
+func (t trees) diff(remote Version, probe Probe) (Leaves, error) {
+	nodes, err := descend(t.nodes, NodeTree, remote.Nodes, probe)
+	if err != nil {
+		return Leaves{}, err
+	}
+	edges, err := descend(t.edges, EdgeTree, remote.Edges, probe)
+	if err != nil {
+		return Leaves{}, err
+	}
+	return Leaves{Nodes: nodes, Edges: edges}, nil
+}
+
+// descend runs Tree.Diff, the root is known from the version.
+func descend(tree *twoPSet.Tree, name string, root twoPSet.Digest, probe Probe) ([]int, error) {
+	return tree.Diff(func(level int, indices []int) ([]twoPSet.Digest, error) {
+		if level == 0 {
+			return []twoPSet.Digest{root}, nil
+		}
+		return probe(HashRequest{Tree: name, Level: level, Indices: indices})
+	})
+}
+
+// Set is the wire form of the operations of a twoPSet.
+type Set[V any] struct {
+	Add    twoPSet.SetOf[V] `json:"add"`
+	Remove twoPSet.SetOf[V] `json:"remove"`
+}
+
+// State is the wire form of the sets of an ElementGraph, or of a part of them.
+type State[N, E any] struct {
+	Nodes Set[*graph.NodeOf[N, E]] `json:"nodes"`
+	Edges Set[*graph.EdgeOf[N, E]] `json:"edges"`
+}
+
+// done is the last message of a session.
+type done struct {
+	Digest twoPSet.Digest `json:"digest"`
+}
+
+// Sync runs a sync session with another replica calling Sync on the other end
+// of the connection. Both sides exchange the Version of their state, then
+// descend together into the Merkle trees of the sets that differ, sending
+// each other the hashes of the nodes that differ level by level. They send
+// each other the operations of the leaves that differ, merge them with Merge
+// and confirm they converged. Payloads must be encodable as JSON.
+//
+// Messages are written and read at the same time, so unbuffered connections
+// such as net.Pipe work. The ElementGraph must not be changed during the
+// session. When an error is returned the connection should be closed, the
+// ElementGraph is left either untouched or with the other side merged in.
+func Sync[N, E any](g *crdt.ElementGraphOf[N, E], rw io.ReadWriter) (Summary, error) {
+	s := &session{enc: json.NewEncoder(rw), dec: json.NewDecoder(rw)}
+	result := Summary{}
+
+	trees := newTrees(g)
+	local := trees.version(g.ReplicaID(), g.Digest())
+	var remote Version
+	if err := s.exchange(local, &remote); err != nil {
+		return result, err
+	}
+	if err := local.Compatible(remote); err != nil {
+		return result, err
+	}
+	result.Peer = remote.Replica
+
+	if remote.Digest != local.Digest {
+		// both sides compare the same hashes, so they ask for the same
+		// nodes in the same order
+		leaves, err := trees.diff(remote, func(r HashRequest) ([]twoPSet.Digest, error) {
+			hashes, err := trees.hashes(r)
+			if err != nil {
+				return nil, err
+			}
+
+			var in []twoPSet.Digest
+			if err := s.exchange(hashes, &in); err != nil {
+				return nil, err
+			}
+			return in, nil
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Leaves = leaves.Len()
+
+		out := Delta(g, leaves)
+		var in State[N, E]
+		if err := s.exchange(out, &in); err != nil {
+			return result, err
+		}
+		result.SentNodes, result.SentEdges = out.Nodes.Len(), out.Edges.Len()
+		result.ReceivedNodes, result.ReceivedEdges = in.Nodes.Len(), in.Edges.Len()
+
+		if err := Apply(g, in); err != nil {
+			return result, err
+		}
+	}
+
+	var end done
+	if err := s.exchange(done{Digest: g.Digest()}, &end); err != nil {
+		return result, err
+	}
+	result.Digest = end.Digest
+	result.Converged = end.Digest == g.Digest()
+
+	return result, nil
+}
+
+// Apply merges the received state into the ElementGraph. The endpoints of
+// the edges are resolved to the nodes of the state or of the ElementGraph.
+func Apply[N, E any](g *crdt.ElementGraphOf[N, E], state State[N, E]) error {
+	nodes := state.Nodes.set()
+	edges := state.Edges.set()
+
+	for _, ops := range []twoPSet.SetOf[*graph.EdgeOf[N, E]]{edges.AddSet, edges.RemoveSet} {
+		for k, op := range ops {
+			if op.Payload == nil || op.Payload.From == nil || op.Payload.To == nil {
+				continue
+			}
+
+			edge := *op.Payload
+			edge.From = resolve(g, nodes, edge.From)
+			edge.To = resolve(g, nodes, edge.To)
+			op.Payload = &edge
+			ops[k] = op
+		}
+	}
+
+	remote := crdt.NewElementGraphOf[N, E]()
+	remote.NodeSet = nodes
+	remote.EdgeSet = edges
+	return g.Merge(remote)
+}
+
+// Export returns the full state of the ElementGraph in wire form.
+func Export[N, E any](g *crdt.ElementGraphOf[N, E]) State[N, E] {
+	return State[N, E]{
+		Nodes: Set[*graph.NodeOf[N, E]]{Add: g.NodeSet.GetAddSet(), Remove: g.NodeSet.GetRemoveSet()},
+		Edges: Set[*graph.EdgeOf[N, E]]{Add: g.EdgeSet.GetAddSet(), Remove: g.EdgeSet.GetRemoveSet()},
+	}
+}
+
+// Len returns the number of operations in the set.
+func (s Set[V]) Len() int {
+	return len(s.Add) + len(s.Remove)
+}
+
+func (s Set[V]) set() *twoPSet.TOf[V] {
+	set := twoPSet.NewOf[V]()
+	twoPSet.Merge(set.AddSet, s.Add)
+	twoPSet.Merge(set.RemoveSet, s.Remove)
+	return set
+}
+
+func newSet[V any](set twoPSet.TwoPSetOf[V]) Set[V] {
+	return Set[V]{Add: set.GetAddSet(), Remove: set.GetRemoveSet()}
+}
+
+func resolve[N, E any](g *crdt.ElementGraphOf[N, E], nodes *twoPSet.TOf[*graph.NodeOf[N, E]], node *graph.NodeOf[N, E]) *graph.NodeOf[N, E] {
+	if op, ok := nodes.AddSet[node.ID]; ok && op.Payload != nil {
+		return op.Payload
+	}
+	if op, ok := g.NodeSet.GetAddSet()[node.ID]; ok && op.Payload != nil {
+		return op.Payload
+	}
+	return node
+}
+
+type session struct {
+	enc *json.Encoder
+	dec *json.Decoder
+}
+
+// exchange writes out while reading in, so both sides can send first.
+func (s *session) exchange(out, in interface{}) error {
+	written := make(chan error, 1)
+	go func() {
+		written <- s.enc.Encode(out)
+	}()
+
+	if err := s.dec.Decode(in); err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	if err := <-written; err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package replication
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/tauki/crdt"
+	"github.com/tauki/crdt/graph"
+	"github.com/tauki/crdt/twoPSet"
+	"net"
+	"testing"
+	"time"
+)
+
+type result struct {
+	summary Summary
+	err     error
+}
+
+func syncPair[N, E any](a, b *crdt.ElementGraphOf[N, E]) (Summary, Summary, error, error) {
+	connA, connB := net.Pipe()
+	defer connA.Close()
+	defer connB.Close()
+
+	done := make(chan result, 1)
+	go func() {
+		s, err := Sync(b, connB)
+		done <- result{s, err}
+	}()
+
+	summaryA, errA := Sync(a, connA)
+	if errA != nil {
+		connA.Close()
+	}
+	r := <-done
+	return summaryA, r.summary, errA, r.err
+}
+
+func TestSync(t *testing.T) {
+	a := crdt.NewElementGraph()
+	b := crdt.NewElementGraph()
+
+	node1 := graph.NewNode(uuid.New(), []byte("a"))
+	node2 := graph.NewNode(uuid.New(), []byte("b"))
+	a.AddNode(node1)
+	a.AddNode(node2)
+	a.AddEdge(graph.NewEdge(uuid.New(), node1, node2))
+	assert.NoError(t, b.Merge(a))
+
+	time.Sleep(1)
+	node3 := graph.NewNode(uuid.New(), []byte("c"))
+	b.AddNode(node3)
+	b.AddEdge(graph.NewEdge(uuid.New(), b.Graph.GetNode(node2.ID), node3))
+	a.RemoveNode(node1)
+
+	summaryA, summaryB, errA, errB := syncPair(a, b)
+	assert.NoError(t, errA)
+	assert.NoError(t, errB)
+
+	assert.True(t, summaryA.Converged)
+	assert.True(t, summaryB.Converged)
+	assert.Equal(t, b.ReplicaID(), summaryA.Peer)
+	assert.Equal(t, summaryA.SentNodes, summaryB.ReceivedNodes)
+	assert.Equal(t, summaryA.SentEdges, summaryB.ReceivedEdges)
+	assert.GreaterOrEqual(t, summaryA.ReceivedNodes, 2)
+	assert.Equal(t, 2, summaryA.ReceivedEdges)
+
+	assert.Equal(t, a.Digest(), b.Digest())
+	assert.Equal(t, a.GraphDigest(), b.GraphDigest())
+	assert.Equal(t, 2, a.Graph.Len())
+	assert.Len(t, a.Graph.Edges(), 1)
+	assert.Equal(t, []byte("b"), a.Graph.Edges()[0].From.Payload)
+	assert.Empty(t, a.Check())
+}
+
+func TestSync_InSync(t *testing.T) {
+	a := crdt.NewElementGraph()
+	a.AddNode(graph.NewNode(uuid.New(), []byte("a")))
+	b := crdt.NewElementGraph()
+	assert.NoError(t, b.Merge(a))
+
+	summaryA, _, errA, errB := syncPair(a, b)
+	assert.NoError(t, errA)
+	assert.NoError(t, errB)
+	assert.True(t, summaryA.Converged)
+	assert.Zero(t, summaryA.Leaves)
+	assert.Zero(t, summaryA.SentNodes+summaryA.ReceivedNodes)
+}
+
+func TestSync_TypedPayloads(t *testing.T) {
+	type task struct {
+		Name string `json:"name"`
+	}
+
+	a := crdt.NewElementGraphOf[task, int]()
+	b := crdt.NewElementGraphOf[task, int]()
+	node1 := graph.NewNodeOf[task, int](uuid.New(), task{Name: "build"})
+	node2 := graph.NewNodeOf[task, int](uuid.New(), task{Name: "test"})
+	a.AddNode(node1)
+	b.AddNode(node2)
+	edge := graph.NewEdgeOf(uuid.New(), node1, node1)
+	edge.Payload = 7
+	a.AddEdge(edge)
+
+	_, _, errA, errB := syncPair(a, b)
+	assert.NoError(t, errA)
+	assert.NoError(t, errB)
+	assert.Equal(t, task{Name: "build"}, b.Graph.GetNode(node1.ID).Payload)
+	assert.Equal(t, 7, b.Graph.Edges()[0].Payload)
+	assert.Equal(t, a.Digest(), b.Digest())
+}
+
+// fakePeer answers the summary of a session with the given messages.
+func fakePeer(t *testing.T, conn net.Conn, messages ...interface{}) {
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for _, m := range messages {
+		var in json.RawMessage
+		if dec.Decode(&in) != nil {
+			return
+		}
+		assert.NoError(t, enc.Encode(m))
+	}
+}
+
+func TestSync_VersionMismatch(t *testing.T) {
+	g := crdt.NewElementGraph()
+	connA, connB := net.Pipe()
+	defer connA.Close()
+	defer connB.Close()
+
+	remote := NewVersion(crdt.NewElementGraph())
+	remote.Protocol = ProtocolVersion + 1
+	go fakePeer(t, connB, remote)
+
+	_, err := Sync(g, connA)
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+}
+
+func TestSync_MalformedState(t *testing.T) {
+	g := crdt.NewElementGraph()
+	g.AddNode(graph.NewNode(uuid.New(), []byte("a")))
+	before := g.Digest()
+
+	// the peer can still regenerate its graph, as only the payload of a
+	// removed node is missing
+	peer := crdt.NewElementGraph()
+	node := graph.NewNode(uuid.New(), []byte("b"))
+	peer.AddNode(node)
+	time.Sleep(1)
+	peer.RemoveNode(node)
+	op := peer.NodeSet.GetRemoveSet()[node.ID]
+	op.Payload = nil
+	peer.NodeSet.GetRemoveSet()[node.ID] = op
+
+	_, _, err, _ := syncPair(g, peer)
+	assert.ErrorIs(t, err, crdt.ErrInvalidState)
+	assert.Equal(t, before, g.Digest())
+	assert.Equal(t, 1, g.Graph.Len())
+}
+
+func TestDiff(t *testing.T) {
+	a := crdt.NewElementGraph()
+	for i := 0; i < 100; i++ {
+		a.AddNode(graph.NewNode(uuid.New(), []byte{byte(i)}))
+	}
+	b := crdt.NewElementGraph()
+	assert.NoError(t, b.Merge(a))
+
+	node := graph.NewNode(uuid.New(), nil)
+	b.AddNode(node)
+
+	requests := make([]HashRequest, 0)
+	leaves, err := Diff(a, NewVersion(b), func(r HashRequest) ([]twoPSet.Digest, error) {
+		requests = append(requests, r)
+		return Hashes(b, r)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Leaves{Nodes: []int{twoPSet.Leaf(node.ID, twoPSet.DefaultTreeDepth)}, Edges: []int{}}, leaves)
+
+	// the edge trees are equal, the node tree is probed under the root and
+	// under the one level 1 node that differs
+	assert.Len(t, requests, 2)
+	for _, r := range requests {
+		assert.Equal(t, NodeTree, r.Tree)
+		assert.Len(t, r.Indices, twoPSet.TreeFanout)
+	}
+
+	_, err = Hashes(b, HashRequest{Tree: "roots", Level: 1, Indices: []int{0}})
+	assert.Error(t, err)
+	_, err = Hashes(b, HashRequest{Tree: NodeTree, Level: 1, Indices: []int{16}})
+	assert.Error(t, err)
+}
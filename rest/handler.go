@@ -0,0 +1,378 @@
+// Package rest serves an ElementGraph as a JSON REST API, so services that do
+// not use the Go package can read and change the graph.
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/tauki/crdt"
+	"github.com/tauki/crdt/graph"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type config struct {
+	lock    sync.Locker
+	maxBody int64
+}
+
+// DefaultMaxBodySize is the largest request body the Handler reads unless set
+// WithMaxBodySize.
+const DefaultMaxBodySize = 1 << 20
+
+type Option func(*config)
+
+// WithLock sets the lock held while the ElementGraph is read or changed. The
+// application, and a replication.Handler or Client serving the same
+// ElementGraph, must hold the same lock. A lock private to the Handler is used
+// otherwise.
+func WithLock(lock sync.Locker) Option {
+	return func(c *config) {
+		c.lock = lock
+	}
+}
+
+// WithMaxBodySize sets the largest request body the Handler reads, larger
+// bodies are refused with 413 Request Entity Too Large. Sizes that are not
+// positive are ignored.
+func WithMaxBodySize(size int64) Option {
+	return func(c *config) {
+		if size > 0 {
+			c.maxBody = size
+		}
+	}
+}
+
+// Handler serves the nodes and edges of an ElementGraph:
+//
+//	GET    /nodes                  all nodes
+//	POST   /nodes                  adds a node, the id is generated if missing
+//	GET    /nodes/{id}             the node
+//	PUT    /nodes/{id}             replaces the payload of the node
+//	DELETE /nodes/{id}             removes the node and its edges
+//	GET    /nodes/{id}/neighbors   the neighbors, ?direction=out, in or both
+//	GET    /nodes/{id}/descendants the nodes reachable from the node
+//	GET    /nodes/{id}/ancestors   the nodes the node is reachable from
+//	GET    /edges                  all edges
+//	POST   /edges                  adds an edge, the id is generated if missing
+//	GET    /edges/{id}             the edge
+//	DELETE /edges/{id}             removes the edge
+//	GET    /path?from={id}&to={id} a path between two nodes, see FindPath
+//
+// Nodes and edges are encoded by their MarshalJSON methods. Adding a node or
+// edge that exists, or an edge the graph refuses, is answered with 409
+// Conflict, an edge with a missing endpoint with 422 Unprocessable Entity,
+// an unknown id with 404 Not Found and a request body larger than the
+// configured size with 413 Request Entity Too Large. Errors are JSON objects
+// with an "error" field.
+type Handler[N, E any] struct {
+	g *crdt.ElementGraphOf[N, E]
+	config
+}
+
+func NewHandler[N, E any](g *crdt.ElementGraphOf[N, E], opts ...Option) *Handler[N, E] {
+	h := &Handler[N, E]{g: g, config: config{lock: &sync.Mutex{}, maxBody: DefaultMaxBodySize}}
+	for _, opt := range opts {
+		opt(&h.config)
+	}
+	return h
+}
+
+// statusError is an error answered with its status code.
+type statusError struct {
+	status int
+	msg    string
+	allow  []string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+func errorf(status int, format string, args ...interface{}) error {
+	return &statusError{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+func (h *Handler[N, E]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	// the body is read before locking, so a slow client does not hold the
+	// lock
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = errorf(http.StatusRequestEntityTooLarge, "request body is larger than %d bytes", h.maxBody)
+		} else {
+			err = errorf(http.StatusBadRequest, "invalid body: %v", err)
+		}
+		writeError(w, err)
+		return
+	}
+
+	// the response is encoded before unlocking, it points into the graph
+	h.lock.Lock()
+	status, v, err := h.route(r, parts, data)
+	var body []byte
+	if err == nil && status != http.StatusNoContent {
+		body, err = json.Marshal(v)
+	}
+	h.lock.Unlock()
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	write(w, status, body)
+}
+
+// route runs the request with the given body and returns the status and body
+// of the response.
+func (h *Handler[N, E]) route(r *http.Request, parts []string, data []byte) (int, interface{}, error) {
+	switch {
+	case len(parts) == 1 && parts[0] == "nodes":
+		switch r.Method {
+		case http.MethodGet:
+			return http.StatusOK, graph.SortNodes(h.g.Graph.Nodes()), nil
+		case http.MethodPost:
+			return h.addNode(data)
+		}
+		return 0, nil, methodNotAllowed(http.MethodGet, http.MethodPost)
+	case len(parts) == 2 && parts[0] == "nodes":
+		switch r.Method {
+		case http.MethodGet:
+			node, err := h.node(parts[1])
+			return http.StatusOK, node, err
+		case http.MethodPut:
+			return h.updateNode(data, parts[1])
+		case http.MethodDelete:
+			node, err := h.node(parts[1])
+			if err != nil {
+				return 0, nil, err
+			}
+			h.g.RemoveNode(node)
+			return http.StatusNoContent, nil, nil
+		}
+		return 0, nil, methodNotAllowed(http.MethodGet, http.MethodPut, http.MethodDelete)
+	case len(parts) == 3 && parts[0] == "nodes":
+		if r.Method != http.MethodGet {
+			return 0, nil, methodNotAllowed(http.MethodGet)
+		}
+		return h.query(r, parts[1], parts[2])
+	case len(parts) == 1 && parts[0] == "edges":
+		switch r.Method {
+		case http.MethodGet:
+			return http.StatusOK, graph.SortEdges(h.g.Graph.Edges()), nil
+		case http.MethodPost:
+			return h.addEdge(data)
+		}
+		return 0, nil, methodNotAllowed(http.MethodGet, http.MethodPost)
+	case len(parts) == 2 && parts[0] == "edges":
+		switch r.Method {
+		case http.MethodGet:
+			edge, err := h.edge(parts[1])
+			return http.StatusOK, edge, err
+		case http.MethodDelete:
+			edge, err := h.edge(parts[1])
+			if err != nil {
+				return 0, nil, err
+			}
+			h.g.RemoveEdge(edge)
+			return http.StatusNoContent, nil, nil
+		}
+		return 0, nil, methodNotAllowed(http.MethodGet, http.MethodDelete)
+	case len(parts) == 1 && parts[0] == "path":
+		if r.Method != http.MethodGet {
+			return 0, nil, methodNotAllowed(http.MethodGet)
+		}
+		return h.path(r)
+	}
+
+	return 0, nil, errorf(http.StatusNotFound, "no such resource %s", r.URL.Path)
+}
+
+func (h *Handler[N, E]) addNode(data []byte) (int, interface{}, error) {
+	var node graph.NodeOf[N, E]
+	if err := json.Unmarshal(data, &node); err != nil {
+		return 0, nil, errorf(http.StatusBadRequest, "invalid node: %v", err)
+	}
+	if node.ID == uuid.Nil {
+		node.ID = uuid.New()
+	}
+
+	if h.g.Graph.GetNode(node.ID) != nil {
+		return 0, nil, errorf(http.StatusConflict, "node %s exists", node.ID)
+	}
+	h.g.AddNode(&node)
+	return http.StatusCreated, &node, nil
+}
+
+func (h *Handler[N, E]) updateNode(data []byte, id string) (int, interface{}, error) {
+	existing, err := h.node(id)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var node graph.NodeOf[N, E]
+	if err := json.Unmarshal(data, &node); err != nil {
+		return 0, nil, errorf(http.StatusBadRequest, "invalid node: %v", err)
+	}
+	if node.ID != uuid.Nil && node.ID != existing.ID {
+		return 0, nil, errorf(http.StatusBadRequest, "node id %s does not match %s", node.ID, existing.ID)
+	}
+
+	node.ID = existing.ID
+	h.g.UpdateNode(&node)
+	return http.StatusOK, h.g.Graph.GetNode(node.ID), nil
+}
+
+func (h *Handler[N, E]) addEdge(data []byte) (int, interface{}, error) {
+	var edge graph.EdgeOf[N, E]
+	if err := json.Unmarshal(data, &edge); err != nil {
+		return 0, nil, errorf(http.StatusBadRequest, "invalid edge: %v", err)
+	}
+	if edge.ID == uuid.Nil {
+		edge.ID = uuid.New()
+	}
+
+	for _, end := range []**graph.NodeOf[N, E]{&edge.From, &edge.To} {
+		node := h.g.Graph.GetNode((*end).ID)
+		if node == nil {
+			return 0, nil, errorf(http.StatusUnprocessableEntity, "endpoint %s does not exist", (*end).ID)
+		}
+		*end = node
+	}
+
+	// in simple graphs AddEdge replaces the id with the one derived from the
+	// endpoints and label, the edge exists if that id is in the graph
+	if !h.g.AddEdge(&edge) {
+		if h.g.Graph.EdgeExists(&edge) {
+			return 0, nil, errorf(http.StatusConflict, "edge %s exists", edge.ID)
+		}
+		return 0, nil, errorf(http.StatusConflict, "edge %s was refused by the graph", edge.ID)
+	}
+	return http.StatusCreated, &edge, nil
+}
+
+func (h *Handler[N, E]) query(r *http.Request, id, name string) (int, interface{}, error) {
+	node, err := h.node(id)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch name {
+	case "neighbors":
+		switch direction := r.URL.Query().Get("direction"); direction {
+		case "", "out":
+			return http.StatusOK, graph.SortNodes(h.g.Graph.OutNeighbors(node.ID)), nil
+		case "in":
+			return http.StatusOK, graph.SortNodes(h.g.Graph.InNeighbors(node.ID)), nil
+		case "both":
+			nodes := append(h.g.Graph.OutNeighbors(node.ID), h.g.Graph.InNeighbors(node.ID)...)
+			return http.StatusOK, unique(nodes), nil
+		default:
+			return 0, nil, errorf(http.StatusBadRequest, "unknown direction %q", direction)
+		}
+	case "descendants":
+		return http.StatusOK, graph.Descendants(h.g.Graph, node), nil
+	case "ancestors":
+		return http.StatusOK, graph.Ancestors(h.g.Graph, node), nil
+	}
+
+	return 0, nil, errorf(http.StatusNotFound, "no such resource %s", r.URL.Path)
+}
+
+func (h *Handler[N, E]) path(r *http.Request) (int, interface{}, error) {
+	from, err := h.node(r.URL.Query().Get("from"))
+	if err != nil {
+		return 0, nil, err
+	}
+	to, err := h.node(r.URL.Query().Get("to"))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	path := h.g.Graph.FindPath(from, to)
+	if len(path) == 0 {
+		return 0, nil, errorf(http.StatusNotFound, "no path from %s to %s", from.ID, to.ID)
+	}
+	return http.StatusOK, path, nil
+}
+
+func (h *Handler[N, E]) node(id string) (*graph.NodeOf[N, E], error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "invalid node id %q", id)
+	}
+
+	node := h.g.Graph.GetNode(parsed)
+	if node == nil {
+		return nil, errorf(http.StatusNotFound, "node %s does not exist", parsed)
+	}
+	return node, nil
+}
+
+func (h *Handler[N, E]) edge(id string) (*graph.EdgeOf[N, E], error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "invalid edge id %q", id)
+	}
+
+	op, ok := h.g.EdgeSet.GetAddSet()[parsed]
+	if ok && op.Payload != nil && op.Payload.From != nil {
+		if from := h.g.Graph.GetNode(op.Payload.From.ID); from != nil {
+			if edge, ok := from.Edges[parsed]; ok {
+				return edge, nil
+			}
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "edge %s does not exist", parsed)
+}
+
+func unique[N, E any](nodes []*graph.NodeOf[N, E]) []*graph.NodeOf[N, E] {
+	seen := make(map[uuid.UUID]bool, len(nodes))
+	result := make([]*graph.NodeOf[N, E], 0, len(nodes))
+	for _, n := range nodes {
+		if !seen[n.ID] {
+			seen[n.ID] = true
+			result = append(result, n)
+		}
+	}
+	return graph.SortNodes(result)
+}
+
+func methodNotAllowed(allowed ...string) error {
+	return &statusError{
+		status: http.StatusMethodNotAllowed,
+		msg:    "method not allowed, use " + strings.Join(allowed, ", "),
+		allow:  allowed,
+	}
+}
+
+func write(w http.ResponseWriter, status int, body []byte) {
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if e, ok := err.(*statusError); ok {
+		status = e.status
+		for _, m := range e.allow {
+			w.Header().Add("Allow", m)
+		}
+	}
+
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	write(w, status, body)
+}
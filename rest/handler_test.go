@@ -0,0 +1,210 @@
+package rest
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/tauki/crdt"
+	"github.com/tauki/crdt/graph"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type node struct {
+	ID      uuid.UUID `json:"id"`
+	Payload string    `json:"payload"`
+}
+
+type edge struct {
+	ID    uuid.UUID `json:"id"`
+	From  uuid.UUID `json:"from"`
+	To    uuid.UUID `json:"to"`
+	Label string    `json:"label"`
+}
+
+func newServer(t *testing.T, opts ...crdt.Option) (*crdt.ElementGraphOf[string, string], *httptest.Server) {
+	g := crdt.NewElementGraphOf[string, string](opts...)
+	server := httptest.NewServer(NewHandler(g))
+	t.Cleanup(server.Close)
+	return g, server
+}
+
+func request(t *testing.T, method, url, body string, out interface{}) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	if out != nil && len(data) > 0 {
+		assert.NoError(t, json.Unmarshal(data, out), string(data))
+	}
+	return resp.StatusCode
+}
+
+func TestHandler_Nodes(t *testing.T) {
+	g, server := newServer(t)
+
+	var created node
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, server.URL+"/nodes", `{"payload":"a"}`, &created))
+	assert.NotEqual(t, uuid.Nil, created.ID)
+	assert.Equal(t, "a", created.Payload)
+
+	id := uuid.New()
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, server.URL+"/nodes",
+		`{"id":"`+id.String()+`","payload":"b"}`, nil))
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, server.URL+"/nodes",
+		`{"id":"`+id.String()+`","payload":"b"}`, nil))
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, server.URL+"/nodes", `{`, nil))
+
+	var nodes []node
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, server.URL+"/nodes", "", &nodes))
+	assert.Len(t, nodes, 2)
+
+	var got node
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPut, server.URL+"/nodes/"+id.String(), `{"payload":"c"}`, &got))
+	assert.Equal(t, "c", got.Payload)
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, server.URL+"/nodes/"+id.String(), "", &got))
+	assert.Equal(t, node{ID: id, Payload: "c"}, got)
+
+	assert.Equal(t, http.StatusNoContent, request(t, http.MethodDelete, server.URL+"/nodes/"+id.String(), "", nil))
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodGet, server.URL+"/nodes/"+id.String(), "", nil))
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodPut, server.URL+"/nodes/"+id.String(), `{"payload":"d"}`, nil))
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodDelete, server.URL+"/nodes/"+id.String(), "", nil))
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodGet, server.URL+"/nodes/nope", "", nil))
+
+	server.Close()
+	assert.Equal(t, 1, g.Graph.Len())
+}
+
+func TestHandler_Edges(t *testing.T) {
+	g, server := newServer(t)
+
+	var a, b node
+	request(t, http.MethodPost, server.URL+"/nodes", `{"payload":"a"}`, &a)
+	request(t, http.MethodPost, server.URL+"/nodes", `{"payload":"b"}`, &b)
+
+	var created edge
+	body := `{"from":"` + a.ID.String() + `","to":"` + b.ID.String() + `","label":"knows"}`
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, server.URL+"/edges", body, &created))
+	assert.Equal(t, edge{ID: created.ID, From: a.ID, To: b.ID, Label: "knows"}, created)
+
+	duplicate := `{"id":"` + created.ID.String() + `","from":"` + a.ID.String() + `","to":"` + b.ID.String() + `"}`
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, server.URL+"/edges", duplicate, nil))
+	missing := `{"from":"` + a.ID.String() + `","to":"` + uuid.New().String() + `"}`
+	var errBody map[string]string
+	assert.Equal(t, http.StatusUnprocessableEntity, request(t, http.MethodPost, server.URL+"/edges", missing, &errBody))
+	assert.Contains(t, errBody["error"], "does not exist")
+
+	var got edge
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, server.URL+"/edges/"+created.ID.String(), "", &got))
+	assert.Equal(t, created, got)
+	var edges []edge
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, server.URL+"/edges", "", &edges))
+	assert.Equal(t, []edge{created}, edges)
+
+	assert.Equal(t, http.StatusNoContent, request(t, http.MethodDelete, server.URL+"/edges/"+created.ID.String(), "", nil))
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodGet, server.URL+"/edges/"+created.ID.String(), "", nil))
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodDelete, server.URL+"/edges/"+created.ID.String(), "", nil))
+
+	server.Close()
+	assert.Empty(t, g.Graph.Edges())
+}
+
+func TestHandler_RefusedEdge(t *testing.T) {
+	g, server := newServer(t, crdt.WithAcyclic())
+	a := graph.NewNodeOf[string, string](uuid.New(), "a")
+	g.AddNode(a)
+
+	body := `{"from":"` + a.ID.String() + `","to":"` + a.ID.String() + `"}`
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, server.URL+"/edges", body, nil))
+}
+
+func TestHandler_SimpleGraphDuplicateEdge(t *testing.T) {
+	g, server := newServer(t, crdt.WithSimpleGraph())
+	a := graph.NewNodeOf[string, string](uuid.New(), "a")
+	b := graph.NewNodeOf[string, string](uuid.New(), "b")
+	g.AddNode(a)
+	g.AddNode(b)
+
+	var created edge
+	body := `{"from":"` + a.ID.String() + `","to":"` + b.ID.String() + `","label":"knows"}`
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, server.URL+"/edges", body, &created))
+	assert.Equal(t, graph.EdgeID(a.ID, b.ID, "knows"), created.ID)
+
+	var errBody map[string]string
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, server.URL+"/edges", body, &errBody))
+	assert.Contains(t, errBody["error"], "exists")
+
+	server.Close()
+	assert.Len(t, g.Graph.Edges(), 1)
+}
+
+func TestHandler_MaxBodySize(t *testing.T) {
+	g := crdt.NewElementGraphOf[string, string]()
+	server := httptest.NewServer(NewHandler(g, WithMaxBodySize(32)))
+	defer server.Close()
+
+	var errBody map[string]string
+	body := `{"payload":"` + strings.Repeat("a", 32) + `"}`
+	assert.Equal(t, http.StatusRequestEntityTooLarge, request(t, http.MethodPost, server.URL+"/nodes", body, &errBody))
+	assert.Contains(t, errBody["error"], "larger than 32 bytes")
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, server.URL+"/nodes", `{"payload":"a"}`, nil))
+
+	server.Close()
+	assert.Equal(t, 1, g.Graph.Len())
+}
+
+func TestHandler_Queries(t *testing.T) {
+	g, server := newServer(t, crdt.WithDeterministicOrder())
+	nodes := make([]*graph.NodeOf[string, string], 4)
+	for i := range nodes {
+		nodes[i] = graph.NewNodeOf[string, string](uuid.New(), string(rune('a'+i)))
+		g.AddNode(nodes[i])
+	}
+	for i := 0; i < 2; i++ {
+		g.AddEdge(graph.NewEdgeOf(uuid.New(), nodes[i], nodes[i+1]))
+	}
+	url := func(i int, query string) string {
+		return server.URL + "/nodes/" + nodes[i].ID.String() + "/" + query
+	}
+
+	var result []node
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, url(1, "neighbors"), "", &result))
+	assert.Equal(t, []node{{nodes[2].ID, "c"}}, result)
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, url(1, "neighbors?direction=in"), "", &result))
+	assert.Equal(t, []node{{nodes[0].ID, "a"}}, result)
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, url(1, "neighbors?direction=both"), "", &result))
+	assert.Len(t, result, 2)
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodGet, url(1, "neighbors?direction=up"), "", nil))
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, url(0, "descendants"), "", &result))
+	assert.Len(t, result, 2)
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, url(2, "ancestors"), "", &result))
+	assert.Len(t, result, 2)
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodGet, url(2, "cousins"), "", nil))
+
+	path := server.URL + "/path?from=" + nodes[0].ID.String() + "&to="
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, path+nodes[2].ID.String(), "", &result))
+	assert.Equal(t, []node{{nodes[0].ID, "a"}, {nodes[1].ID, "b"}, {nodes[2].ID, "c"}}, result)
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodGet, path+nodes[3].ID.String(), "", nil))
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodGet, path+uuid.New().String(), "", nil))
+}
+
+func TestHandler_Routing(t *testing.T) {
+	_, server := newServer(t)
+
+	req, _ := http.NewRequest(http.MethodPatch, server.URL+"/nodes", nil)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, []string{http.MethodGet, http.MethodPost}, resp.Header.Values("Allow"))
+
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodGet, server.URL+"/graphs", "", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, request(t, http.MethodPost, server.URL+"/path", "", nil))
+}
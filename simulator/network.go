@@ -0,0 +1,123 @@
+package simulator
+
+import (
+	"container/heap"
+)
+
+// envelope is a message in flight between two replicas.
+type envelope struct {
+	from, to int
+	// at is the tick the message is delivered at, seq orders the messages
+	// delivered at the same tick
+	at, seq int
+	data    []byte
+}
+
+// queue holds the messages in flight, ordered by delivery.
+type queue []*envelope
+
+func (q queue) Len() int {
+	return len(q)
+}
+
+func (q queue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *queue) Push(x interface{}) {
+	*q = append(*q, x.(*envelope))
+}
+
+func (q *queue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return e
+}
+
+// send puts the message on the network, unless it is lost. The message may
+// be duplicated, and overtakes the messages sent before it on the same link
+// only when it is reordered.
+func (s *Simulator) send(from, to int, data []byte) {
+	s.stats.Sent++
+	if s.chance(s.loss) {
+		s.stats.Lost++
+		return
+	}
+
+	copies := 1
+	if s.chance(s.duplication) {
+		s.stats.Duplicated++
+		copies++
+	}
+
+	for i := 0; i < copies; i++ {
+		at := s.now + s.minDelay + s.rng.Intn(s.maxDelay-s.minDelay+1)
+		link := [2]int{from, to}
+		if s.chance(s.reordering) {
+			s.stats.Reordered++
+		} else if at < s.last[link] {
+			at = s.last[link]
+		}
+		if at > s.last[link] {
+			s.last[link] = at
+		}
+
+		s.seq++
+		heap.Push(&s.queue, &envelope{from: from, to: to, at: at, seq: s.seq, data: data})
+	}
+}
+
+// deliver hands the messages due at the current tick to their replicas.
+// Messages crossing a partition are dropped on delivery.
+func (s *Simulator) deliver() error {
+	for len(s.queue) > 0 && s.queue[0].at <= s.now {
+		e := heap.Pop(&s.queue).(*envelope)
+		if s.partitioned(e.from, e.to) {
+			s.stats.Partitioned++
+			continue
+		}
+
+		s.stats.Delivered++
+		if err := s.receive(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Partition splits the replicas into the given groups of replica indices.
+// Messages between replicas of different groups are dropped until Heal.
+// Replicas not in any group are cut off from every other replica.
+func (s *Simulator) Partition(groups ...[]int) {
+	s.groups = make([]int, len(s.replicas))
+	for i := range s.groups {
+		s.groups[i] = -1 - i
+	}
+	for g, group := range groups {
+		for _, i := range group {
+			s.groups[i] = g
+		}
+	}
+}
+
+// Heal removes the partition, messages still in flight are delivered.
+func (s *Simulator) Heal() {
+	s.groups = nil
+}
+
+func (s *Simulator) partitioned(a, b int) bool {
+	return s.groups != nil && s.groups[a] != s.groups[b]
+}
+
+func (s *Simulator) chance(p float64) bool {
+	return p > 0 && s.rng.Float64() < p
+}
@@ -0,0 +1,432 @@
+// Package simulator runs ElementGraph replicas over a simulated network, to
+// test that they converge. The network delays, loses, duplicates and
+// reorders messages and can be partitioned, while a random workload of node
+// and edge changes runs on the replicas. Time is counted in ticks, the
+// operations are timestamped from the ticks and every random choice is drawn
+// from the seed, so a run can be replayed down to the digests of the
+// replicas.
+//
+// Replicas exchange state the way the replication package does: every
+// interval each replica sends the Version of its state to a random peer. When
+// their roots differ, the two descend into the Merkle trees of their sets,
+// every message carrying the hashes of the nodes that still differ one level
+// further down, and the replica that reaches the leaves answers with its
+// operations there. Lost messages end the exchange, the next version starts
+// a new one.
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/tauki/crdt"
+	"github.com/tauki/crdt/graph"
+	"github.com/tauki/crdt/replication"
+	"github.com/tauki/crdt/twoPSet"
+	"math/rand"
+	"time"
+)
+
+// ErrNotConverged is returned by Settle when the replicas still differ.
+var ErrNotConverged = errors.New("replicas did not converge")
+
+type config struct {
+	replicas    int
+	minDelay    int
+	maxDelay    int
+	loss        float64
+	duplication float64
+	reordering  float64
+	interval    int
+	operations  int
+	graphOpts   []crdt.Option
+}
+
+type Option func(*config)
+
+// WithReplicas sets the number of replicas, 3 by default.
+func WithReplicas(n int) Option {
+	return func(c *config) {
+		c.replicas = n
+	}
+}
+
+// WithDelay sets the range of ticks a message takes to be delivered, 1 by
+// default. Delays shorter than a tick are raised to one tick.
+func WithDelay(min, max int) Option {
+	return func(c *config) {
+		c.minDelay = min
+		c.maxDelay = max
+	}
+}
+
+// WithLoss sets the probability of a message being lost.
+func WithLoss(p float64) Option {
+	return func(c *config) {
+		c.loss = p
+	}
+}
+
+// WithDuplication sets the probability of a message being delivered twice.
+func WithDuplication(p float64) Option {
+	return func(c *config) {
+		c.duplication = p
+	}
+}
+
+// WithReordering sets the probability of a message overtaking the messages
+// sent before it between the same replicas. Messages between two replicas
+// are delivered in order otherwise.
+func WithReordering(p float64) Option {
+	return func(c *config) {
+		c.reordering = p
+	}
+}
+
+// WithInterval sets how many ticks pass between two versions sent by a
+// replica, 1 by default.
+func WithInterval(ticks int) Option {
+	return func(c *config) {
+		c.interval = ticks
+	}
+}
+
+// WithOperations sets how many operations of the workload run every Step,
+// 1 by default.
+func WithOperations(n int) Option {
+	return func(c *config) {
+		c.operations = n
+	}
+}
+
+// WithGraphOptions sets the options the ElementGraph of every replica is
+// created with, WithReplicaID is set by the simulator.
+func WithGraphOptions(opts ...crdt.Option) Option {
+	return func(c *config) {
+		c.graphOpts = opts
+	}
+}
+
+// Stats counts what happened during a run.
+type Stats struct {
+	// Operations counts the AddNode, AddEdge, RemoveNode and RemoveEdge
+	// calls of the workload.
+	Operations int
+	// Sent counts the messages sent by the replicas, Delivered the ones
+	// that reached their replica.
+	Sent      int
+	Delivered int
+	// Lost, Duplicated and Reordered count the messages lost, duplicated
+	// and reordered by the network, Partitioned the ones dropped by a
+	// partition.
+	Lost        int
+	Duplicated  int
+	Reordered   int
+	Partitioned int
+}
+
+// Simulator is a set of replicas and the network between them.
+type Simulator struct {
+	config
+	rng      *rand.Rand
+	replicas []*crdt.ElementGraph
+	now      int
+	seq      int
+	queue    queue
+	last     map[[2]int]int
+	groups   []int
+	stats    Stats
+	// stamps counts the timestamps handed out by clock
+	stamps int
+}
+
+// message is what replicas send each other: the version of the sender, the
+// hashes of a level of its trees, or the operations of the leaves that
+// differ.
+type message struct {
+	Version *replication.Version               `json:"version,omitempty"`
+	Probe   *probe                             `json:"probe,omitempty"`
+	State   *replication.State[[]byte, []byte] `json:"state,omitempty"`
+}
+
+// probe holds the hashes of the sender for the nodes of one level of its
+// trees, the children of the nodes that differed on the level above.
+type probe struct {
+	Level      int              `json:"level"`
+	Nodes      []int            `json:"nodes"`
+	Edges      []int            `json:"edges"`
+	NodeHashes []twoPSet.Digest `json:"nodeHashes"`
+	EdgeHashes []twoPSet.Digest `json:"edgeHashes"`
+}
+
+func New(seed int64, opts ...Option) *Simulator {
+	c := config{replicas: 3, minDelay: 1, maxDelay: 1, interval: 1, operations: 1}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.minDelay < 1 {
+		c.minDelay = 1
+	}
+	if c.maxDelay < c.minDelay {
+		c.maxDelay = c.minDelay
+	}
+	if c.interval < 1 {
+		c.interval = 1
+	}
+
+	s := &Simulator{
+		config: c,
+		rng:    rand.New(rand.NewSource(seed)),
+		last:   make(map[[2]int]int),
+	}
+	for i := 0; i < c.replicas; i++ {
+		opts := append([]crdt.Option{crdt.WithDeterministicOrder()}, c.graphOpts...)
+		opts = append(opts, crdt.WithReplicaID(s.uuid()), crdt.WithClock(s.clock))
+		s.replicas = append(s.replicas, crdt.NewElementGraph(opts...))
+	}
+	return s
+}
+
+// Replicas returns the ElementGraph of every replica.
+func (s *Simulator) Replicas() []*crdt.ElementGraph {
+	return s.replicas
+}
+
+// Now returns the number of ticks run so far.
+func (s *Simulator) Now() int {
+	return s.now
+}
+
+func (s *Simulator) Stats() Stats {
+	return s.stats
+}
+
+// InFlight returns the number of messages not delivered yet.
+func (s *Simulator) InFlight() int {
+	return len(s.queue)
+}
+
+// Step runs one tick: the operations of the workload, the versions sent by
+// the replicas and the delivery of the messages due.
+func (s *Simulator) Step() error {
+	for i := 0; i < s.operations; i++ {
+		s.operate()
+	}
+	return s.tick()
+}
+
+// Run runs the given number of Steps.
+func (s *Simulator) Run(ticks int) error {
+	for i := 0; i < ticks; i++ {
+		if err := s.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Settle runs ticks without workload until the replicas converge, for at
+// most the given number of ticks. It returns the number of ticks it ran, and
+// ErrNotConverged if the replicas still differ.
+func (s *Simulator) Settle(ticks int) (int, error) {
+	for i := 0; i < ticks; i++ {
+		if s.Converged() == nil {
+			return i, nil
+		}
+		if err := s.tick(); err != nil {
+			return i + 1, err
+		}
+	}
+
+	if err := s.Converged(); err != nil {
+		return ticks, fmt.Errorf("%w after %d ticks: %v", ErrNotConverged, ticks, err)
+	}
+	return ticks, nil
+}
+
+// Converged checks that every replica has the same Digest and GraphDigest as
+// the first one, and no violations of its invariants.
+func (s *Simulator) Converged() error {
+	first := s.replicas[0]
+	for i, g := range s.replicas {
+		if v := g.Check(); len(v) > 0 {
+			return fmt.Errorf("replica %d: %d violations, first: %v", i, len(v), v[0])
+		}
+		if g.Digest() != first.Digest() {
+			return fmt.Errorf("replica %d: digest %s, replica 0: %s", i, g.Digest(), first.Digest())
+		}
+		if g.GraphDigest() != first.GraphDigest() {
+			return fmt.Errorf("replica %d: graph differs from replica 0", i)
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) tick() error {
+	s.now++
+	if len(s.replicas) > 1 && s.now%s.interval == 0 {
+		for i, g := range s.replicas {
+			peer := s.rng.Intn(len(s.replicas) - 1)
+			if peer >= i {
+				peer++
+			}
+
+			version := replication.NewVersion(g)
+			s.send(i, peer, s.encode(message{Version: &version}))
+		}
+	}
+	return s.deliver()
+}
+
+// receive takes a message of the exchange started by a version one step
+// further, and merges received operations.
+func (s *Simulator) receive(e *envelope) error {
+	var m message
+	if err := json.Unmarshal(e.data, &m); err != nil {
+		return fmt.Errorf("replica %d: message from %d: %w", e.to, e.from, err)
+	}
+
+	g := s.replicas[e.to]
+	switch {
+	case m.Version != nil:
+		local := replication.NewVersion(g)
+		if err := local.Compatible(*m.Version); err != nil {
+			return fmt.Errorf("replica %d: version from %d: %w", e.to, e.from, err)
+		}
+		if local.Digest == m.Version.Digest {
+			return nil
+		}
+
+		nodes, edges := make([]int, 0), make([]int, 0)
+		if local.Nodes != m.Version.Nodes {
+			nodes = append(nodes, 0)
+		}
+		if local.Edges != m.Version.Edges {
+			edges = append(edges, 0)
+		}
+		return s.descend(e, g, 0, nodes, edges)
+	case m.Probe != nil:
+		p := m.Probe
+		nodes, err := differ(g, replication.NodeTree, p.Level, p.Nodes, p.NodeHashes)
+		if err != nil {
+			return fmt.Errorf("replica %d: probe from %d: %w", e.to, e.from, err)
+		}
+		edges, err := differ(g, replication.EdgeTree, p.Level, p.Edges, p.EdgeHashes)
+		if err != nil {
+			return fmt.Errorf("replica %d: probe from %d: %w", e.to, e.from, err)
+		}
+		if len(nodes)+len(edges) == 0 {
+			return nil
+		}
+		return s.descend(e, g, p.Level, nodes, edges)
+	case m.State != nil:
+		if err := replication.Apply(g, *m.State); err != nil {
+			return fmt.Errorf("replica %d: state from %d: %w", e.to, e.from, err)
+		}
+	}
+	return nil
+}
+
+// descend answers the sender of e, given the nodes of the given level that
+// differ: with the operations of the leaves at the bottom of the trees, or
+// with the hashes of their children otherwise.
+func (s *Simulator) descend(e *envelope, g *crdt.ElementGraph, level int, nodes, edges []int) error {
+	if level == twoPSet.DefaultTreeDepth {
+		state := replication.Delta(g, replication.Leaves{Nodes: nodes, Edges: edges})
+		s.send(e.to, e.from, s.encode(message{State: &state}))
+		return nil
+	}
+
+	p := &probe{Level: level + 1, Nodes: children(nodes), Edges: children(edges)}
+	var err error
+	if p.NodeHashes, err = replication.Hashes(g, replication.HashRequest{Tree: replication.NodeTree, Level: p.Level, Indices: p.Nodes}); err != nil {
+		return err
+	}
+	if p.EdgeHashes, err = replication.Hashes(g, replication.HashRequest{Tree: replication.EdgeTree, Level: p.Level, Indices: p.Edges}); err != nil {
+		return err
+	}
+	s.send(e.to, e.from, s.encode(message{Probe: p}))
+	return nil
+}
+
+// differ returns the indices whose local hashes differ from the given ones.
+func differ(g *crdt.ElementGraph, tree string, level int, indices []int, remote []twoPSet.Digest) ([]int, error) {
+	local, err := replication.Hashes(g, replication.HashRequest{Tree: tree, Level: level, Indices: indices})
+	if err != nil {
+		return nil, err
+	}
+	if len(remote) != len(local) {
+		return nil, fmt.Errorf("got %d hashes for %d nodes", len(remote), len(local))
+	}
+
+	differ := make([]int, 0)
+	for i, h := range local {
+		if h != remote[i] {
+			differ = append(differ, indices[i])
+		}
+	}
+	return differ, nil
+}
+
+func children(indices []int) []int {
+	children := make([]int, 0, len(indices)*twoPSet.TreeFanout)
+	for _, i := range indices {
+		for c := i * twoPSet.TreeFanout; c < (i+1)*twoPSet.TreeFanout; c++ {
+			children = append(children, c)
+		}
+	}
+	return children
+}
+
+func (s *Simulator) encode(m message) []byte {
+	data, err := json.Marshal(m)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// operate runs one random operation on a random replica. Edges and removals
+// need existing elements, a node is added when there are none.
+func (s *Simulator) operate() {
+	g := s.replicas[s.rng.Intn(len(s.replicas))]
+	nodes := g.Graph.Nodes()
+	edges := g.Graph.Edges()
+	s.stats.Operations++
+
+	switch n := s.rng.Intn(100); {
+	case n < 30 && len(nodes) > 0:
+		from := nodes[s.rng.Intn(len(nodes))]
+		to := nodes[s.rng.Intn(len(nodes))]
+		g.AddEdge(graph.NewEdge(s.uuid(), from, to))
+	case n < 45 && len(nodes) > 0:
+		g.RemoveNode(nodes[s.rng.Intn(len(nodes))])
+	case n < 60 && len(edges) > 0:
+		g.RemoveEdge(edges[s.rng.Intn(len(edges))])
+	default:
+		g.AddNode(graph.NewNode(s.uuid(), s.payload()))
+	}
+}
+
+// clock timestamps the operations of the replicas from the simulated time, a
+// second per tick, so they do not depend on when the run happens. Every
+// timestamp is a nanosecond later than the previous one.
+func (s *Simulator) clock() time.Time {
+	s.stamps++
+	return time.Unix(0, 0).UTC().Add(time.Duration(s.now)*time.Second + time.Duration(s.stamps))
+}
+
+func (s *Simulator) uuid() uuid.UUID {
+	id, err := uuid.NewRandomFromReader(s.rng)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+func (s *Simulator) payload() []byte {
+	payload := make([]byte, 1+s.rng.Intn(8))
+	s.rng.Read(payload)
+	return payload
+}
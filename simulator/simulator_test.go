@@ -0,0 +1,82 @@
+package simulator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/tauki/crdt"
+	"testing"
+)
+
+func TestSimulator_Converges(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		s := New(seed,
+			WithReplicas(5),
+			WithDelay(1, 6),
+			WithLoss(0.2),
+			WithDuplication(0.1),
+			WithReordering(0.3),
+			WithOperations(3),
+		)
+
+		assert.NoError(t, s.Run(50))
+		s.Partition([]int{0, 1}, []int{2, 3})
+		assert.NoError(t, s.Run(50))
+		s.Heal()
+
+		_, err := s.Settle(500)
+		assert.NoError(t, err, "seed %d", seed)
+
+		stats := s.Stats()
+		assert.Equal(t, 300, stats.Operations)
+		assert.NotZero(t, stats.Lost)
+		assert.NotZero(t, stats.Duplicated)
+		assert.NotZero(t, stats.Reordered)
+		assert.NotZero(t, stats.Partitioned)
+		assert.NotZero(t, s.Replicas()[0].Graph.Len())
+	}
+}
+
+func TestSimulator_Deterministic(t *testing.T) {
+	run := func(seed int64) (*Simulator, int) {
+		s := New(seed, WithReplicas(4), WithDelay(1, 4), WithLoss(0.1), WithDuplication(0.1))
+		assert.NoError(t, s.Run(40))
+		ticks, err := s.Settle(500)
+		assert.NoError(t, err)
+		return s, ticks
+	}
+
+	s, ticks := run(42)
+	again, ticksAgain := run(42)
+	assert.Equal(t, s.Stats(), again.Stats())
+	assert.Equal(t, ticks, ticksAgain)
+	assert.Equal(t, s.Replicas()[0].Digest(), again.Replicas()[0].Digest())
+	assert.Equal(t, s.Replicas()[0].GraphDigest(), again.Replicas()[0].GraphDigest())
+
+	other, _ := run(43)
+	assert.NotEqual(t, s.Replicas()[0].Digest(), other.Replicas()[0].Digest())
+}
+
+func TestSimulator_Partition(t *testing.T) {
+	s := New(7, WithReplicas(3))
+	s.Partition([]int{0, 1})
+	assert.NoError(t, s.Run(30))
+
+	_, err := s.Settle(50)
+	assert.ErrorIs(t, err, ErrNotConverged)
+
+	s.Heal()
+	_, err = s.Settle(100)
+	assert.NoError(t, err)
+}
+
+func TestSimulator_EdgePolicies(t *testing.T) {
+	for _, policy := range []crdt.EdgePolicy{crdt.KeepDangling, crdt.AddWins, crdt.RemoveWins} {
+		s := New(3, WithGraphOptions(crdt.WithEdgePolicy(policy)), WithDelay(1, 3), WithInterval(3))
+		assert.NoError(t, s.Run(60))
+
+		_, err := s.Settle(200)
+		assert.NoError(t, err, "policy %d", policy)
+		for _, g := range s.Replicas() {
+			assert.Equal(t, policy, g.EdgePolicy())
+		}
+	}
+}
@@ -1,77 +1,261 @@
 package twoPSet
 
 import (
+	"bytes"
 	"errors"
 	"github.com/google/uuid"
+	"sort"
 	"time"
 )
 
-type TwoPSet interface {
-	GetAddSet() Set
-	GetRemoveSet() Set
-	Add(uuid.UUID, interface{})
+// TwoPSetOf is a set whose elements carry payloads of type V.
+type TwoPSetOf[V any] interface {
+	GetAddSet() SetOf[V]
+	GetRemoveSet() SetOf[V]
+	GetHistory() HistoryOf[V]
+	Add(uuid.UUID, V)
 	Remove(uuid.UUID) error
-	Merge(TwoPSet)
+	Merge(TwoPSetOf[V])
+	Fork(...Option) TwoPSetOf[V]
+	Digest() Digest
 }
 
-type OP struct {
-	Payload   interface{}
+type OPOf[V any] struct {
+	Payload   V
 	Timestamp time.Time
+	Replica   uuid.UUID
 }
 
-type Set map[uuid.UUID]OP
+type SetOf[V any] map[uuid.UUID]OPOf[V]
 
-type T struct {
-	AddSet    Set
-	RemoveSet Set
+type EventKind int
+
+const (
+	Added EventKind = iota
+	Updated
+	Removed
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Updated:
+		return "updated"
+	case Removed:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// EventOf is a single operation applied to an element, as recorded in the
+// History of a set.
+type EventOf[V any] struct {
+	OPOf[V]
+	Kind EventKind
+}
+
+// HistoryOf keeps every Event of every element, ordered by timestamp.
+type HistoryOf[V any] map[uuid.UUID][]EventOf[V]
+
+type TOf[V any] struct {
+	AddSet    SetOf[V]
+	RemoveSet SetOf[V]
+	Replica   uuid.UUID
+	History   HistoryOf[V]
+
+	// digest is kept up to date by every change made through the methods
+	digest Digest
+	// shared records the maps shared with a fork, each is copied before its
+	// next change
+	shared sharing
+	// now timestamps the operations, time.Now unless set WithClock
+	now func() time.Time
+}
+
+type sharing struct {
+	add, remove, history bool
+}
+
+type config struct {
+	replica uuid.UUID
+	history bool
+	now     func() time.Time
+}
+
+type Option func(*config)
+
+// WithReplica sets the replica id recorded in the operations of the set.
+func WithReplica(id uuid.UUID) Option {
+	return func(c *config) {
+		c.replica = id
+	}
+}
+
+// WithHistory makes the set record every add, update and remove in its
+// History, including the ones received through Merge.
+func WithHistory() Option {
+	return func(c *config) {
+		c.history = true
+	}
 }
 
-func New() *T {
-	return &T{
-		AddSet:    make(Set, 0),
-		RemoveSet: make(Set, 0),
+// WithClock sets the clock the operations of the set are timestamped with,
+// e.g. to replay changes in tests. The clock must not go backwards.
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
 	}
 }
 
+func NewOf[V any](opts ...Option) *TOf[V] {
+	c := config{}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	t := &TOf[V]{
+		AddSet:    make(SetOf[V], 0),
+		RemoveSet: make(SetOf[V], 0),
+		Replica:   c.replica,
+		now:       c.now,
+	}
+	if c.history {
+		t.History = make(HistoryOf[V])
+	}
+
+	return t
+}
+
 var _ TwoPSet = &T{}
 
-func (t *T) GetAddSet() Set {
+func (t *TOf[V]) GetAddSet() SetOf[V] {
 	return t.AddSet
 }
 
-func (t *T) GetRemoveSet() Set {
+func (t *TOf[V]) GetRemoveSet() SetOf[V] {
 	return t.RemoveSet
 }
 
-func (t *T) Add(id uuid.UUID, payload interface{}) {
-	t.AddSet[id] = OP{
-		Timestamp: time.Now().UTC(),
+// GetHistory returns the recorded history, or nil if the set was created
+// without WithHistory.
+func (t *TOf[V]) GetHistory() HistoryOf[V] {
+	return t.History
+}
+
+func (t *TOf[V]) Add(id uuid.UUID, payload V) {
+	t.detach(true, false)
+
+	kind := t.addKind(id)
+	t.put(t.AddSet, addEntry, id, OPOf[V]{
+		Timestamp: t.clock().UTC(),
 		Payload:   payload,
-	}
+		Replica:   t.Replica,
+	})
+	t.record(id, EventOf[V]{OPOf: t.AddSet[id], Kind: kind})
 }
 
-func (t *T) Remove(id uuid.UUID) error {
+func (t *TOf[V]) Remove(id uuid.UUID) error {
 	if _, ok := t.AddSet[id]; !ok {
 		return errors.New("element does not exist")
 	}
+	t.detach(false, true)
 
-	t.RemoveSet[id] = OP{
-		Timestamp: time.Now(),
+	t.put(t.RemoveSet, removeEntry, id, OPOf[V]{
+		Timestamp: t.clock(),
 		Payload:   t.AddSet[id].Payload,
-	}
+		Replica:   t.Replica,
+	})
+	t.record(id, EventOf[V]{OPOf: t.RemoveSet[id], Kind: Removed})
 	return nil
 }
 
-func (t *T) Merge(set TwoPSet) {
-	t.AddSet = Merge(t.AddSet, set.GetAddSet())
-	t.RemoveSet = Merge(t.RemoveSet, set.GetRemoveSet())
+func (t *TOf[V]) Merge(set TwoPSetOf[V]) {
+	t.detach(true, true)
+
+	if t.History != nil {
+		t.mergeHistory(set)
+	}
+
+	t.merge(t.AddSet, addEntry, set.GetAddSet())
+	t.merge(t.RemoveSet, removeEntry, set.GetRemoveSet())
+}
+
+// merge is Merge into one of the sets of t, keeping the digest up to date.
+func (t *TOf[V]) merge(set SetOf[V], kind byte, other SetOf[V]) {
+	for k, v := range other {
+		if n, ok := set[k]; !ok || n.Before(v) {
+			t.put(set, kind, k, v)
+		}
+	}
 }
 
-func Merge(setA, setB Set) Set {
+// put stores the operation in one of the sets of t and updates the digest.
+func (t *TOf[V]) put(set SetOf[V], kind byte, id uuid.UUID, op OPOf[V]) {
+	if old, ok := set[id]; ok {
+		t.digest.xor(entryHash(kind, id, old))
+	}
+	set[id] = op
+	t.digest.xor(entryHash(kind, id, op))
+}
+
+// Fork returns an independent copy of the set with a new replica id, so the
+// operations of the fork are told apart from the ones of t. Only WithReplica
+// and WithClock apply, the fork keeps the clock of t otherwise. The copy
+// shares its maps with t, and each of them copies a map before it changes it.
+func (t *TOf[V]) Fork(opts ...Option) TwoPSetOf[V] {
+	c := config{replica: uuid.New(), now: t.now}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	t.shared = sharing{add: true, remove: true, history: true}
+	return &TOf[V]{
+		AddSet:    t.AddSet,
+		RemoveSet: t.RemoveSet,
+		Replica:   c.replica,
+		History:   t.History,
+		digest:    t.digest,
+		shared:    t.shared,
+		now:       c.now,
+	}
+}
+
+func (t *TOf[V]) clock() time.Time {
+	if t.now == nil {
+		return time.Now()
+	}
+	return t.now()
+}
+
+// detach copies the maps about to change that are still shared with a fork.
+// The history is copied shallowly, record never appends in place.
+func (t *TOf[V]) detach(add, remove bool) {
+	if add && t.shared.add {
+		t.AddSet = Merge(make(SetOf[V], len(t.AddSet)), t.AddSet)
+		t.shared.add = false
+	}
+	if remove && t.shared.remove {
+		t.RemoveSet = Merge(make(SetOf[V], len(t.RemoveSet)), t.RemoveSet)
+		t.shared.remove = false
+	}
+	if t.shared.history {
+		if t.History != nil {
+			history := make(HistoryOf[V], len(t.History))
+			for k, v := range t.History {
+				history[k] = v
+			}
+			t.History = history
+		}
+		t.shared.history = false
+	}
+}
+
+func Merge[V any](setA, setB SetOf[V]) SetOf[V] {
 	for k, v := range setB {
 		n, ok := setA[k]
 		if ok {
-			if n.Timestamp.Before(v.Timestamp) {
+			if n.Before(v) {
 				setA[k] = v
 			}
 		} else {
@@ -81,3 +265,71 @@ func Merge(setA, setB Set) Set {
 
 	return setA
 }
+
+// Before orders operations by timestamp. Operations with the same timestamp
+// are ordered by replica id, so every replica picks the same one on Merge.
+func (op OPOf[V]) Before(other OPOf[V]) bool {
+	if !op.Timestamp.Equal(other.Timestamp) {
+		return op.Timestamp.Before(other.Timestamp)
+	}
+	return bytes.Compare(op.Replica[:], other.Replica[:]) < 0
+}
+
+// mergeHistory records the events of the incoming set. Operations of a set
+// without history are recorded as well, so the latest state of every element
+// is always part of the history.
+func (t *TOf[V]) mergeHistory(set TwoPSetOf[V]) {
+	for k, events := range set.GetHistory() {
+		for _, e := range events {
+			t.record(k, e)
+		}
+	}
+
+	for k, v := range set.GetAddSet() {
+		t.record(k, EventOf[V]{OPOf: v, Kind: t.addKind(k)})
+	}
+
+	for k, v := range set.GetRemoveSet() {
+		t.record(k, EventOf[V]{OPOf: v, Kind: Removed})
+	}
+}
+
+// addKind is the kind of an add of the element: Updated while the element is
+// live, Added if it was never added or was removed after its latest add.
+func (t *TOf[V]) addKind(id uuid.UUID) EventKind {
+	added, ok := t.AddSet[id]
+	if !ok {
+		return Added
+	}
+	if removed, ok := t.RemoveSet[id]; ok && removed.Timestamp.After(added.Timestamp) {
+		return Added
+	}
+	return Updated
+}
+
+// record appends the event to the history of the element, unless the same
+// operation was already recorded.
+func (t *TOf[V]) record(id uuid.UUID, event EventOf[V]) {
+	if t.History == nil {
+		return
+	}
+
+	events := t.History[id]
+	for _, e := range events {
+		if e.Replica == event.Replica &&
+			e.Timestamp.Equal(event.Timestamp) &&
+			(e.Kind == Removed) == (event.Kind == Removed) {
+			return
+		}
+	}
+
+	// the slice may be shared with a fork, it is copied rather than grown
+	events = append(events[:len(events):len(events)], event)
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].OPOf.Before(events[j].OPOf) || events[j].OPOf.Before(events[i].OPOf) {
+			return events[i].OPOf.Before(events[j].OPOf)
+		}
+		return events[i].Kind < events[j].Kind
+	})
+	t.History[id] = events
+}
@@ -8,26 +8,80 @@ import (
 	uuid "github.com/google/uuid"
 )
 
-// MockTwoPSet is an autogenerated mock type for the TwoPSet type
-type MockTwoPSet struct {
+// MockTwoPSetOf is an autogenerated mock type for the TwoPSetOf type
+type MockTwoPSetOf[V any] struct {
 	mock.Mock
 }
 
 // Add provides a mock function with given fields: _a0, _a1
-func (_m *MockTwoPSet) Add(_a0 uuid.UUID, _a1 interface{}) {
+func (_m *MockTwoPSetOf[V]) Add(_a0 uuid.UUID, _a1 V) {
 	_m.Called(_a0, _a1)
 }
 
+// Digest provides a mock function with given fields:
+func (_m *MockTwoPSetOf[V]) Digest() Digest {
+	ret := _m.Called()
+
+	var r0 Digest
+	if rf, ok := ret.Get(0).(func() Digest); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(Digest)
+		}
+	}
+
+	return r0
+}
+
+// Fork provides a mock function with given fields: _a0
+func (_m *MockTwoPSetOf[V]) Fork(_a0 ...Option) TwoPSetOf[V] {
+	_va := make([]interface{}, len(_a0))
+	for _i := range _a0 {
+		_va[_i] = _a0[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 TwoPSetOf[V]
+	if rf, ok := ret.Get(0).(func(...Option) TwoPSetOf[V]); ok {
+		r0 = rf(_a0...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(TwoPSetOf[V])
+		}
+	}
+
+	return r0
+}
+
 // GetAddSet provides a mock function with given fields:
-func (_m *MockTwoPSet) GetAddSet() Set {
+func (_m *MockTwoPSetOf[V]) GetAddSet() SetOf[V] {
+	ret := _m.Called()
+
+	var r0 SetOf[V]
+	if rf, ok := ret.Get(0).(func() SetOf[V]); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(SetOf[V])
+		}
+	}
+
+	return r0
+}
+
+// GetHistory provides a mock function with given fields:
+func (_m *MockTwoPSetOf[V]) GetHistory() HistoryOf[V] {
 	ret := _m.Called()
 
-	var r0 Set
-	if rf, ok := ret.Get(0).(func() Set); ok {
+	var r0 HistoryOf[V]
+	if rf, ok := ret.Get(0).(func() HistoryOf[V]); ok {
 		r0 = rf()
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(Set)
+			r0 = ret.Get(0).(HistoryOf[V])
 		}
 	}
 
@@ -35,15 +89,15 @@ func (_m *MockTwoPSet) GetAddSet() Set {
 }
 
 // GetRemoveSet provides a mock function with given fields:
-func (_m *MockTwoPSet) GetRemoveSet() Set {
+func (_m *MockTwoPSetOf[V]) GetRemoveSet() SetOf[V] {
 	ret := _m.Called()
 
-	var r0 Set
-	if rf, ok := ret.Get(0).(func() Set); ok {
+	var r0 SetOf[V]
+	if rf, ok := ret.Get(0).(func() SetOf[V]); ok {
 		r0 = rf()
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(Set)
+			r0 = ret.Get(0).(SetOf[V])
 		}
 	}
 
@@ -51,12 +105,12 @@ func (_m *MockTwoPSet) GetRemoveSet() Set {
 }
 
 // Merge provides a mock function with given fields: _a0
-func (_m *MockTwoPSet) Merge(_a0 TwoPSet) {
+func (_m *MockTwoPSetOf[V]) Merge(_a0 TwoPSetOf[V]) {
 	_m.Called(_a0)
 }
 
 // Remove provides a mock function with given fields: _a0
-func (_m *MockTwoPSet) Remove(_a0 uuid.UUID) error {
+func (_m *MockTwoPSetOf[V]) Remove(_a0 uuid.UUID) error {
 	ret := _m.Called(_a0)
 
 	var r0 error
@@ -68,3 +122,6 @@ func (_m *MockTwoPSet) Remove(_a0 uuid.UUID) error {
 
 	return r0
 }
+
+// MockTwoPSet is a mock of the TwoPSet type
+type MockTwoPSet = MockTwoPSetOf[interface{}]
@@ -40,6 +40,24 @@ func TestT_Remove(t *testing.T) {
 	assert.Equal(t, payload, removeSet[id].Payload)
 }
 
+func TestT_WithClock(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	set := NewOf[string](WithClock(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}))
+
+	id := uuid.New()
+	set.Add(id, "hello")
+	assert.NoError(t, set.Remove(id))
+	assert.True(t, set.GetAddSet()[id].Timestamp.Equal(time.Date(2020, 1, 1, 0, 0, 1, 0, time.UTC)))
+	assert.True(t, set.GetRemoveSet()[id].Timestamp.Equal(time.Date(2020, 1, 1, 0, 0, 2, 0, time.UTC)))
+
+	fork := set.Fork()
+	fork.Add(id, "again")
+	assert.True(t, fork.GetAddSet()[id].Timestamp.Equal(time.Date(2020, 1, 1, 0, 0, 3, 0, time.UTC)))
+}
+
 func TestT_Remove_ElementDoesntExist(t *testing.T) {
 	set := New()
 	id := uuid.New()
@@ -107,3 +125,233 @@ func TestMerge_Conflict(t *testing.T) {
 	assert.Contains(t, setMerged, id)
 	assert.Equal(t, payload2, setMerged[id].Payload)
 }
+
+func TestWithReplica(t *testing.T) {
+	replica := uuid.New()
+	set := New(WithReplica(replica))
+
+	id := uuid.New()
+	set.Add(id, []byte("hello"))
+	assert.NoError(t, set.Remove(id))
+
+	assert.Equal(t, replica, set.GetAddSet()[id].Replica)
+	assert.Equal(t, replica, set.GetRemoveSet()[id].Replica)
+}
+
+func TestT_GetHistory_Disabled(t *testing.T) {
+	set := New()
+	set.Add(uuid.New(), []byte("hello"))
+	assert.Nil(t, set.GetHistory())
+}
+
+func TestT_History(t *testing.T) {
+	replica := uuid.New()
+	set := New(WithReplica(replica), WithHistory())
+
+	id := uuid.New()
+	set.Add(id, []byte("hello"))
+	time.Sleep(1)
+	set.Add(id, []byte("world"))
+	time.Sleep(1)
+	assert.NoError(t, set.Remove(id))
+
+	events := set.GetHistory()[id]
+	assert.Len(t, events, 3)
+	assert.Equal(t, Added, events[0].Kind)
+	assert.Equal(t, []byte("hello"), events[0].Payload)
+	assert.Equal(t, Updated, events[1].Kind)
+	assert.Equal(t, []byte("world"), events[1].Payload)
+	assert.Equal(t, Removed, events[2].Kind)
+	for _, e := range events {
+		assert.Equal(t, replica, e.Replica)
+	}
+}
+
+func TestT_History_ReAdd(t *testing.T) {
+	now := time.Now()
+	set := New(WithHistory(), WithClock(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}))
+
+	id := uuid.New()
+	set.Add(id, []byte("hello"))
+	assert.NoError(t, set.Remove(id))
+	set.Add(id, []byte("again"))
+	set.Add(id, []byte("world"))
+
+	kinds := make([]EventKind, 0)
+	for _, e := range set.GetHistory()[id] {
+		kinds = append(kinds, e.Kind)
+	}
+	assert.Equal(t, []EventKind{Added, Removed, Added, Updated}, kinds)
+
+	// an add merged from a set without history re-adds the removed element
+	removed := New(WithHistory(), WithClock(func() time.Time { return now.Add(time.Minute) }))
+	removed.Merge(set)
+	assert.NoError(t, removed.Remove(id))
+	plain := New(WithClock(func() time.Time { return now.Add(time.Hour) }))
+	plain.Add(id, []byte("merged"))
+	removed.Merge(plain)
+
+	events := removed.GetHistory()[id]
+	assert.Equal(t, Removed, events[len(events)-2].Kind)
+	assert.Equal(t, Added, events[len(events)-1].Kind)
+	assert.Equal(t, []byte("merged"), events[len(events)-1].Payload)
+}
+
+func TestT_Merge_History(t *testing.T) {
+	replicaA := uuid.New()
+	replicaB := uuid.New()
+	setA := New(WithReplica(replicaA), WithHistory())
+	setB := New(WithReplica(replicaB), WithHistory())
+
+	id := uuid.New()
+	setA.Add(id, []byte("hello"))
+	setB.Merge(setA)
+	time.Sleep(1)
+	assert.NoError(t, setB.Remove(id))
+
+	setA.Merge(setB)
+	setA.Merge(setB)
+
+	events := setA.GetHistory()[id]
+	assert.Len(t, events, 2)
+	assert.Equal(t, Added, events[0].Kind)
+	assert.Equal(t, replicaA, events[0].Replica)
+	assert.Equal(t, Removed, events[1].Kind)
+	assert.Equal(t, replicaB, events[1].Replica)
+	assert.Equal(t, setA.GetHistory(), setB.GetHistory())
+}
+
+func TestT_Merge_HistoryFromSetWithoutHistory(t *testing.T) {
+	setA := New(WithHistory())
+	setB := New(WithReplica(uuid.New()))
+
+	id := uuid.New()
+	setB.Add(id, []byte("hello"))
+	assert.NoError(t, setB.Remove(id))
+
+	setA.Merge(setB)
+
+	events := setA.GetHistory()[id]
+	assert.Len(t, events, 2)
+	assert.Equal(t, Added, events[0].Kind)
+	assert.Equal(t, Removed, events[1].Kind)
+	assert.Equal(t, setB.Replica, events[1].Replica)
+}
+
+func TestEventKind_String(t *testing.T) {
+	assert.Equal(t, "added", Added.String())
+	assert.Equal(t, "updated", Updated.String())
+	assert.Equal(t, "removed", Removed.String())
+	assert.Equal(t, "unknown", EventKind(-1).String())
+}
+
+func TestT_Fork(t *testing.T) {
+	set := New(WithHistory())
+	id := uuid.New()
+	set.Add(id, []byte("hello"))
+
+	fork := set.Fork().(*T)
+	assert.Equal(t, set.AddSet, fork.AddSet)
+	assert.NotEqual(t, set.Replica, fork.Replica)
+
+	forkID := uuid.New()
+	fork.Add(forkID, []byte("world"))
+	assert.NoError(t, fork.Remove(id))
+
+	assert.Contains(t, fork.AddSet, forkID)
+	assert.NotContains(t, set.AddSet, forkID)
+	assert.NotContains(t, set.RemoveSet, id)
+	assert.Len(t, set.History[id], 1)
+	assert.Len(t, fork.History[id], 2)
+
+	setID := uuid.New()
+	set.Add(setID, []byte("!"))
+	assert.NotContains(t, fork.AddSet, setID)
+
+	set.Merge(fork)
+	assert.Contains(t, set.AddSet, forkID)
+	assert.Contains(t, set.RemoveSet, id)
+	assert.NotContains(t, fork.AddSet, setID)
+}
+
+func TestT_Fork_SharesUntilChanged(t *testing.T) {
+	set := New()
+	set.Add(uuid.New(), []byte("hello"))
+
+	all := sharing{add: true, remove: true, history: true}
+	fork := set.Fork().(*T)
+	assert.Equal(t, all, set.shared)
+	assert.Equal(t, all, fork.shared)
+
+	err := fork.Remove(uuid.New())
+	assert.Error(t, err)
+	assert.Equal(t, all, fork.shared)
+
+	fork.Add(uuid.New(), []byte("world"))
+	assert.Equal(t, sharing{remove: true}, fork.shared)
+	assert.Equal(t, all, set.shared)
+	assert.Len(t, set.AddSet, 1)
+
+	set.Merge(fork)
+	assert.Equal(t, sharing{}, set.shared)
+	assert.Len(t, set.AddSet, 2)
+}
+
+func TestT_Fork_WithReplica(t *testing.T) {
+	id := uuid.New()
+	set := New(WithHistory())
+	set.Add(id, []byte("hello"))
+
+	replica := uuid.New()
+	fork := set.Fork(WithReplica(replica)).(*T)
+	assert.Equal(t, replica, fork.Replica)
+
+	// the fork appends to a copy of the shared history of the element
+	fork.Add(id, []byte("world"))
+	assert.Len(t, set.History[id], 1)
+	assert.Len(t, fork.History[id], 2)
+	assert.Equal(t, replica, fork.History[id][1].Replica)
+}
+
+func TestOP_Before(t *testing.T) {
+	now := time.Now()
+	replicaA := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	replicaB := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+
+	assert.True(t, OP{Timestamp: now}.Before(OP{Timestamp: now.Add(1)}))
+	assert.False(t, OP{Timestamp: now.Add(1)}.Before(OP{Timestamp: now}))
+	assert.True(t, OP{Timestamp: now, Replica: replicaA}.Before(OP{Timestamp: now, Replica: replicaB}))
+	assert.False(t, OP{Timestamp: now, Replica: replicaB}.Before(OP{Timestamp: now, Replica: replicaA}))
+	assert.False(t, OP{Timestamp: now, Replica: replicaA}.Before(OP{Timestamp: now, Replica: replicaA}))
+}
+
+func TestMerge_SameTimestampConverges(t *testing.T) {
+	now := time.Now()
+	id := uuid.New()
+
+	setA := Set{id: OP{Timestamp: now, Replica: uuid.New(), Payload: []byte("hello")}}
+	setB := Set{id: OP{Timestamp: now, Replica: uuid.New(), Payload: []byte("world")}}
+
+	mergedA := Merge(Set{id: setA[id]}, setB)
+	mergedB := Merge(Set{id: setB[id]}, setA)
+	assert.Equal(t, mergedA, mergedB)
+}
+
+func TestTOf_TypedPayloads(t *testing.T) {
+	a := NewOf[string]()
+	b := NewOf[string]()
+	id := uuid.New()
+
+	a.Add(id, "first")
+	time.Sleep(1)
+	b.Add(id, "second")
+
+	a.Merge(b)
+	assert.Equal(t, "second", a.GetAddSet()[id].Payload)
+
+	assert.NoError(t, a.Remove(id))
+	assert.Equal(t, "second", a.GetRemoveSet()[id].Payload)
+}
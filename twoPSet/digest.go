@@ -0,0 +1,71 @@
+package twoPSet
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
+)
+
+const (
+	addEntry byte = iota
+	removeEntry
+)
+
+// Digest is a hash of the operations in the AddSet and RemoveSet of a set.
+// Sets with the same operations have the same digest, no matter in which
+// order the operations were added or merged.
+type Digest [sha256.Size]byte
+
+func (d Digest) String() string {
+	return hex.EncodeToString(d[:])
+}
+
+func (d Digest) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Digest) UnmarshalText(text []byte) error {
+	if hex.DecodedLen(len(text)) != len(d) {
+		return fmt.Errorf("digest must be %d hex characters", 2*len(d))
+	}
+	_, err := hex.Decode(d[:], text)
+	return err
+}
+
+func (d *Digest) xor(other Digest) {
+	for i := range d {
+		d[i] ^= other[i]
+	}
+}
+
+// Digest returns the digest of the set. It combines a hash of the id,
+// timestamp and replica of every operation, so it is updated in constant time
+// on every change and merge. Payloads are not part of the digest, neither is
+// the history, and changes made directly to AddSet or RemoveSet are not
+// reflected.
+func (t *TOf[V]) Digest() Digest {
+	return t.digest
+}
+
+// DigestOf computes the digest of the given sets from scratch.
+func DigestOf[V any](addSet, removeSet SetOf[V]) Digest {
+	var d Digest
+	for k, op := range addSet {
+		d.xor(entryHash(addEntry, k, op))
+	}
+	for k, op := range removeSet {
+		d.xor(entryHash(removeEntry, k, op))
+	}
+	return d
+}
+
+func entryHash[V any](kind byte, id uuid.UUID, op OPOf[V]) Digest {
+	buf := make([]byte, 0, 1+16+8+16)
+	buf = append(buf, kind)
+	buf = append(buf, id[:]...)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(op.Timestamp.UnixNano()))
+	buf = append(buf, op.Replica[:]...)
+	return sha256.Sum256(buf)
+}
@@ -0,0 +1,60 @@
+package twoPSet
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestT_Digest(t *testing.T) {
+	a := New(WithReplica(uuid.New()))
+	b := New(WithReplica(uuid.New()))
+	c := New(WithReplica(uuid.New()))
+	assert.Equal(t, a.Digest(), b.Digest())
+
+	id1, id2, id3 := uuid.New(), uuid.New(), uuid.New()
+	a.Add(id1, "a")
+	time.Sleep(1)
+	b.Add(id2, "b")
+	b.Add(id1, "b")
+	assert.NotEqual(t, a.Digest(), b.Digest())
+	assert.Equal(t, DigestOf(b.AddSet, b.RemoveSet), b.Digest())
+
+	c.Add(id3, "c")
+	assert.NoError(t, c.Remove(id3))
+	assert.Equal(t, DigestOf(c.AddSet, c.RemoveSet), c.Digest())
+
+	a.Merge(b)
+	a.Merge(c)
+	c.Merge(a)
+	b.Merge(c)
+	assert.Equal(t, a.Digest(), b.Digest())
+	assert.Equal(t, a.Digest(), c.Digest())
+	assert.Equal(t, DigestOf(a.AddSet, a.RemoveSet), a.Digest())
+}
+
+func TestT_Digest_Fork(t *testing.T) {
+	set := New()
+	set.Add(uuid.New(), "a")
+
+	fork := set.Fork()
+	assert.Equal(t, set.Digest(), fork.Digest())
+
+	fork.Add(uuid.New(), "b")
+	assert.NotEqual(t, set.Digest(), fork.Digest())
+	assert.Equal(t, DigestOf(set.AddSet, set.RemoveSet), set.Digest())
+}
+
+func TestDigest_Text(t *testing.T) {
+	set := New()
+	set.Add(uuid.New(), "a")
+
+	text, err := set.Digest().MarshalText()
+	assert.NoError(t, err)
+
+	var d Digest
+	assert.NoError(t, d.UnmarshalText(text))
+	assert.Equal(t, set.Digest(), d)
+	assert.Error(t, d.UnmarshalText([]byte("abc")))
+}
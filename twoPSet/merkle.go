@@ -0,0 +1,194 @@
+package twoPSet
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/google/uuid"
+)
+
+const (
+	// TreeFanout is the number of children of every inner node of a Tree,
+	// every level splits the ids by one more hex digit.
+	TreeFanout = 16
+	// MaxTreeDepth is the deepest Tree that can be built, with 65536 leaves.
+	MaxTreeDepth = 4
+	// DefaultTreeDepth gives a Tree with 256 leaves.
+	DefaultTreeDepth = 2
+)
+
+// Tree is a Merkle tree over the id space of the AddSet and RemoveSet of a
+// set. Every leaf covers the ids starting with the same Depth hex digits and
+// hashes their operations like Digest does, every inner node hashes its
+// children. Two replicas compare their roots and descend only into the
+// subtrees that differ, see Diff, then exchange the operations of the leaves
+// that differ with Delta and merge them with Merge.
+type Tree struct {
+	Depth int
+	// levels[0] holds the root, levels[i] the TreeFanout^i nodes of level i
+	levels [][]Digest
+}
+
+// NewTree builds the tree of the set with the given depth, which is capped at
+// MaxTreeDepth.
+func NewTree[V any](set TwoPSetOf[V], depth int) *Tree {
+	if depth < 0 {
+		depth = 0
+	}
+	if depth > MaxTreeDepth {
+		depth = MaxTreeDepth
+	}
+
+	t := &Tree{Depth: depth, levels: make([][]Digest, depth+1)}
+	for i := range t.levels {
+		t.levels[i] = make([]Digest, pow(TreeFanout, i))
+	}
+
+	leaves := t.levels[depth]
+	for k, op := range set.GetAddSet() {
+		leaves[Leaf(k, depth)].xor(entryHash(addEntry, k, op))
+	}
+	for k, op := range set.GetRemoveSet() {
+		leaves[Leaf(k, depth)].xor(entryHash(removeEntry, k, op))
+	}
+
+	for level := depth - 1; level >= 0; level-- {
+		children := t.levels[level+1]
+		for i := range t.levels[level] {
+			h := sha256.New()
+			for _, c := range children[i*TreeFanout : (i+1)*TreeFanout] {
+				h.Write(c[:])
+			}
+			copy(t.levels[level][i][:], h.Sum(nil))
+		}
+	}
+
+	return t
+}
+
+// Root returns the hash of the root, equal roots mean equal sets.
+func (t *Tree) Root() Digest {
+	return t.levels[0][0]
+}
+
+// Hashes returns the hashes of the nodes with the given indices at the given
+// level, level 0 being the root and level Depth the leaves. The children of
+// node i are the nodes i*TreeFanout to (i+1)*TreeFanout-1 of the next level.
+// It fails on indices outside of the tree, so it can answer remote requests.
+func (t *Tree) Hashes(level int, indices []int) ([]Digest, error) {
+	if level < 0 || level > t.Depth {
+		return nil, fmt.Errorf("level %d is outside of a tree of depth %d", level, t.Depth)
+	}
+
+	hashes := make([]Digest, 0, len(indices))
+	for _, i := range indices {
+		if i < 0 || i >= len(t.levels[level]) {
+			return nil, fmt.Errorf("node %d is outside of level %d", i, level)
+		}
+		hashes = append(hashes, t.levels[level][i])
+	}
+	return hashes, nil
+}
+
+// Diff compares t with a remote tree of the same depth and returns the
+// leaves where they differ, in increasing order. The remote tree is only asked
+// for the root and, level by level, for the children of the nodes that
+// differ, so it can be a Tree on another replica behind a network call.
+func (t *Tree) Diff(remote func(level int, indices []int) ([]Digest, error)) ([]int, error) {
+	differ := []int{0}
+	for level := 0; level <= t.Depth && len(differ) > 0; level++ {
+		hashes, err := remote(level, differ)
+		if err != nil {
+			return nil, err
+		}
+		if len(hashes) != len(differ) {
+			return nil, fmt.Errorf("got %d hashes for %d nodes of level %d", len(hashes), len(differ), level)
+		}
+
+		next := make([]int, 0)
+		for j, i := range differ {
+			if hashes[j] == t.levels[level][i] {
+				continue
+			}
+			if level == t.Depth {
+				next = append(next, i)
+				continue
+			}
+			for c := i * TreeFanout; c < (i+1)*TreeFanout; c++ {
+				next = append(next, c)
+			}
+		}
+		differ = next
+	}
+
+	return differ, nil
+}
+
+// Delta returns a set holding the operations of the given set whose ids fall
+// into the given leaves of a tree of the given depth. It is merged into the
+// other replica with Merge.
+func Delta[V any](set TwoPSetOf[V], depth int, leaves []int) *TOf[V] {
+	in := make(map[int]bool, len(leaves))
+	for _, l := range leaves {
+		in[l] = true
+	}
+
+	delta := NewOf[V]()
+	for k, op := range set.GetAddSet() {
+		if in[Leaf(k, depth)] {
+			delta.AddSet[k] = op
+		}
+	}
+	for k, op := range set.GetRemoveSet() {
+		if in[Leaf(k, depth)] {
+			delta.RemoveSet[k] = op
+		}
+	}
+	delta.digest = DigestOf(delta.AddSet, delta.RemoveSet)
+	return delta
+}
+
+// AntiEntropy brings two sets in sync by comparing their trees of the given
+// depth and merging into each of them only the operations of the other one in
+// the leaves that differ. It returns those leaves.
+func AntiEntropy[V any](a, b TwoPSetOf[V], depth int) ([]int, error) {
+	treeA, treeB := NewTree(a, depth), NewTree(b, depth)
+
+	leaves, err := treeA.Diff(treeB.Hashes)
+	if err != nil {
+		return nil, err
+	}
+	if len(leaves) == 0 {
+		return leaves, nil
+	}
+
+	fromA, fromB := Delta(a, treeA.Depth, leaves), Delta(b, treeB.Depth, leaves)
+	a.Merge(fromB)
+	b.Merge(fromA)
+	return leaves, nil
+}
+
+// Leaf returns the leaf of a tree of the given depth that covers the id, the
+// number formed by its first depth hex digits.
+func Leaf(id uuid.UUID, depth int) int {
+	if depth > MaxTreeDepth {
+		depth = MaxTreeDepth
+	}
+
+	leaf := 0
+	for i := 0; i < depth; i++ {
+		b := id[i/2]
+		if i%2 == 0 {
+			b >>= 4
+		}
+		leaf = leaf*TreeFanout + int(b&0x0f)
+	}
+	return leaf
+}
+
+func pow(base, exp int) int {
+	n := 1
+	for i := 0; i < exp; i++ {
+		n *= base
+	}
+	return n
+}
@@ -0,0 +1,101 @@
+package twoPSet
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLeaf(t *testing.T) {
+	id := uuid.MustParse("a3c00000-0000-0000-0000-000000000000")
+	assert.Equal(t, 0, Leaf(id, 0))
+	assert.Equal(t, 0xa, Leaf(id, 1))
+	assert.Equal(t, 0xa3, Leaf(id, 2))
+	assert.Equal(t, 0xa3c, Leaf(id, 3))
+}
+
+func TestTree_Diff(t *testing.T) {
+	a := New(WithReplica(uuid.New()))
+	for i := 0; i < 200; i++ {
+		a.Add(uuid.New(), i)
+	}
+	b := New(WithReplica(uuid.New()))
+	b.Merge(a)
+	assert.Equal(t, NewTree(a, 2).Root(), NewTree(b, 2).Root())
+
+	added := uuid.MustParse("12000000-0000-0000-0000-000000000000")
+	time.Sleep(1)
+	b.Add(added, "b")
+	var removed uuid.UUID
+	for k := range a.AddSet {
+		removed = k
+		break
+	}
+	assert.NoError(t, a.Remove(removed))
+
+	treeA, treeB := NewTree(a, 2), NewTree(b, 2)
+	assert.NotEqual(t, treeA.Root(), treeB.Root())
+
+	asked := 0
+	leaves, err := treeA.Diff(func(level int, indices []int) ([]Digest, error) {
+		asked += len(indices)
+		return treeB.Hashes(level, indices)
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, uniqueInts(Leaf(added, 2), Leaf(removed, 2)), leaves)
+	assert.LessOrEqual(t, asked, 1+2*TreeFanout+2*TreeFanout)
+
+	delta := Delta(b, 2, leaves)
+	assert.Contains(t, delta.AddSet, added)
+	assert.LessOrEqual(t, len(delta.AddSet), 1+len(b.AddSet)*2/TreeFanout)
+}
+
+func TestTree_Hashes_OutOfRange(t *testing.T) {
+	tree := NewTree(New(), 1)
+	_, err := tree.Hashes(2, []int{0})
+	assert.Error(t, err)
+	_, err = tree.Hashes(1, []int{TreeFanout})
+	assert.Error(t, err)
+}
+
+func TestAntiEntropy(t *testing.T) {
+	a := New(WithReplica(uuid.New()))
+	b := New(WithReplica(uuid.New()))
+	for i := 0; i < 50; i++ {
+		a.Add(uuid.New(), i)
+	}
+	b.Merge(a)
+
+	leaves, err := AntiEntropy[interface{}](a, b, DefaultTreeDepth)
+	assert.NoError(t, err)
+	assert.Empty(t, leaves)
+
+	var id uuid.UUID
+	for k := range b.AddSet {
+		id = k
+		break
+	}
+	a.Add(uuid.New(), "a")
+	time.Sleep(1)
+	b.Add(uuid.New(), "b")
+	assert.NoError(t, b.Remove(id))
+
+	_, err = AntiEntropy[interface{}](a, b, DefaultTreeDepth)
+	assert.NoError(t, err)
+	assert.Equal(t, a.Digest(), b.Digest())
+	assert.Equal(t, NewTree(a, 3).Root(), NewTree(b, 3).Root())
+	assert.Contains(t, a.RemoveSet, id)
+}
+
+func uniqueInts(values ...int) []int {
+	seen := make(map[int]bool)
+	unique := make([]int, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,14 @@
+package twoPSet
+
+// The types below are the set with untyped payloads.
+
+type TwoPSet = TwoPSetOf[interface{}]
+type OP = OPOf[interface{}]
+type Set = SetOf[interface{}]
+type Event = EventOf[interface{}]
+type History = HistoryOf[interface{}]
+type T = TOf[interface{}]
+
+func New(opts ...Option) *T {
+	return NewOf[interface{}](opts...)
+}
@@ -0,0 +1,134 @@
+package crdt
+
+import (
+	"errors"
+	"github.com/tauki/crdt/graph"
+)
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+	// ErrUndoConflict is returned when the element of the operation being
+	// undone or redone was changed by a merge in a way that makes the
+	// operation impossible, e.g. undoing AddNode of a node that another
+	// replica already removed. The operation is dropped from the stack.
+	ErrUndoConflict = errors.New("element was changed by a merge")
+)
+
+type opKind int
+
+const (
+	opAddNode opKind = iota
+	opAddEdge
+	opRemoveNode
+	opRemoveEdge
+)
+
+// operation is a local change kept on the undo and redo stacks. For node
+// operations, edges are the edges that were removed or restored with the node.
+type operation[N, E any] struct {
+	kind  opKind
+	node  *graph.NodeOf[N, E]
+	edge  *graph.EdgeOf[N, E]
+	edges []*graph.EdgeOf[N, E]
+}
+
+func (op operation[N, E]) inverse() operation[N, E] {
+	switch op.kind {
+	case opAddNode:
+		op.kind = opRemoveNode
+	case opRemoveNode:
+		op.kind = opAddNode
+	case opAddEdge:
+		op.kind = opRemoveEdge
+	case opRemoveEdge:
+		op.kind = opAddEdge
+	}
+	return op
+}
+
+// record pushes a local operation on the undo stack. A new operation makes
+// the operations that were undone before it impossible to redo.
+func (s *ElementGraphOf[N, E]) record(op operation[N, E]) {
+	s.undo = append(s.undo, op)
+	s.redo = nil
+}
+
+func (s *ElementGraphOf[N, E]) CanUndo() bool {
+	return len(s.undo) > 0
+}
+
+func (s *ElementGraphOf[N, E]) CanRedo() bool {
+	return len(s.redo) > 0
+}
+
+// Undo reverts the last local AddNode, AddEdge, RemoveNode or RemoveEdge by
+// applying the opposite operation, so the undo replicates like any other
+// change. Undoing RemoveNode restores the edges that were removed with it.
+func (s *ElementGraphOf[N, E]) Undo() error {
+	if len(s.undo) == 0 {
+		return ErrNothingToUndo
+	}
+
+	op := s.undo[len(s.undo)-1]
+	s.undo = s.undo[:len(s.undo)-1]
+
+	applied, ok := s.apply(op.inverse())
+	if !ok {
+		return ErrUndoConflict
+	}
+
+	s.redo = append(s.redo, applied.inverse())
+	return nil
+}
+
+// Redo applies the last undone operation again.
+func (s *ElementGraphOf[N, E]) Redo() error {
+	if len(s.redo) == 0 {
+		return ErrNothingToRedo
+	}
+
+	op := s.redo[len(s.redo)-1]
+	s.redo = s.redo[:len(s.redo)-1]
+
+	applied, ok := s.apply(op)
+	if !ok {
+		return ErrUndoConflict
+	}
+
+	s.undo = append(s.undo, applied)
+	return nil
+}
+
+// apply runs the operation as a new local change and returns it as it was
+// applied, or false if the current state of the element does not allow it.
+func (s *ElementGraphOf[N, E]) apply(op operation[N, E]) (operation[N, E], bool) {
+	switch op.kind {
+	case opAddNode:
+		if s.Graph.GetNode(op.node.ID) != nil {
+			return op, false
+		}
+
+		// the removed node still holds its outgoing edges, which are
+		// restored one by one below
+		node := graph.NewNodeOf[N, E](op.node.ID, op.node.Payload)
+		s.addNode(node)
+
+		edges := make([]*graph.EdgeOf[N, E], 0, len(op.edges))
+		for _, edge := range op.edges {
+			if s.addEdge(edge) {
+				edges = append(edges, edge)
+			}
+		}
+		return operation[N, E]{kind: opAddNode, node: node, edges: edges}, true
+	case opRemoveNode:
+		node, edges, ok := s.removeNode(op.node)
+		return operation[N, E]{kind: opRemoveNode, node: node, edges: edges}, ok
+	case opAddEdge:
+		return op, s.addEdge(op.edge)
+	case opRemoveEdge:
+		return op, s.removeEdge(op.edge)
+	}
+
+	return op, false
+}
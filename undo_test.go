@@ -0,0 +1,228 @@
+package crdt
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/tauki/crdt/graph"
+	"github.com/tauki/crdt/twoPSet"
+	"testing"
+	"time"
+)
+
+func TestElementGraph_Undo_NothingToUndo(t *testing.T) {
+	g := NewElementGraph()
+	assert.False(t, g.CanUndo())
+	assert.Equal(t, ErrNothingToUndo, g.Undo())
+}
+
+func TestElementGraph_Redo_NothingToRedo(t *testing.T) {
+	g := NewElementGraph()
+	assert.False(t, g.CanRedo())
+	assert.Equal(t, ErrNothingToRedo, g.Redo())
+}
+
+func TestElementGraph_Undo_AddNode(t *testing.T) {
+	g := NewElementGraph()
+
+	node := graph.NewNode(uuid.New(), []byte("hello"))
+	g.AddNode(node)
+	assert.True(t, g.CanUndo())
+
+	time.Sleep(1)
+	assert.NoError(t, g.Undo())
+	assert.False(t, g.Graph.NodeExists(node))
+	assert.Contains(t, g.NodeSet.GetRemoveSet(), node.ID)
+	assert.True(t, g.CanRedo())
+
+	time.Sleep(1)
+	assert.NoError(t, g.Redo())
+	assert.True(t, g.Graph.NodeExists(node))
+	assert.Equal(t, []byte("hello"), g.Graph.GetNode(node.ID).Payload)
+
+	g.RegenerateGraph()
+	assert.True(t, g.Graph.NodeExists(node))
+}
+
+func TestElementGraph_Undo_AddEdge(t *testing.T) {
+	g := NewElementGraph()
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	edge := graph.NewEdge(uuid.New(), node1, node2)
+	g.AddNode(node1)
+	g.AddNode(node2)
+	g.AddEdge(edge)
+
+	time.Sleep(1)
+	assert.NoError(t, g.Undo())
+	assert.False(t, g.Graph.EdgeExists(edge))
+	assert.True(t, g.Graph.NodeExists(node2))
+
+	time.Sleep(1)
+	assert.NoError(t, g.Redo())
+	assert.True(t, g.Graph.EdgeExists(edge))
+
+	g.RegenerateGraph()
+	assert.True(t, g.Graph.EdgeExists(edge))
+}
+
+func TestElementGraph_Undo_RemoveNodeRestoresEdges(t *testing.T) {
+	g := NewElementGraph()
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	node3 := graph.NewNode(uuid.New(), []byte("!"))
+	edge12 := graph.NewEdge(uuid.New(), node1, node2)
+	edge23 := graph.NewEdge(uuid.New(), node2, node3)
+	g.AddNode(node1)
+	g.AddNode(node2)
+	g.AddNode(node3)
+	g.AddEdge(edge12)
+	g.AddEdge(edge23)
+
+	time.Sleep(1)
+	g.RemoveNode(node2)
+	assert.False(t, g.Graph.EdgeExists(edge12))
+	assert.False(t, g.Graph.EdgeExists(edge23))
+
+	time.Sleep(1)
+	assert.NoError(t, g.Undo())
+	assert.True(t, g.Graph.NodeExists(node2))
+	assert.True(t, g.Graph.EdgeExists(edge12))
+	assert.True(t, g.Graph.EdgeExists(edge23))
+
+	g.RegenerateGraph()
+	assert.True(t, g.Graph.NodeExists(node2))
+	assert.True(t, g.Graph.EdgeExists(edge12))
+	assert.True(t, g.Graph.EdgeExists(edge23))
+
+	time.Sleep(1)
+	assert.NoError(t, g.Redo())
+	assert.False(t, g.Graph.NodeExists(node2))
+	assert.False(t, g.Graph.EdgeExists(edge12))
+	assert.False(t, g.Graph.EdgeExists(edge23))
+}
+
+func TestElementGraph_Undo_RemoveNodeAttachesDanglingEdges(t *testing.T) {
+	g1 := NewElementGraph()
+	g2 := NewElementGraph()
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g1.AddNode(node1)
+	g1.AddNode(node2)
+	assert.NoError(t, g2.Merge(g1))
+
+	g2.RemoveNode(g2.Graph.GetNode(node2.ID))
+	time.Sleep(1)
+	edge := graph.NewEdge(uuid.New(), node1, node2)
+	g1.AddEdge(edge)
+	assert.NoError(t, g2.Merge(g1))
+	assert.Equal(t, []*graph.Edge{edge}, g2.DanglingEdges())
+
+	time.Sleep(1)
+	assert.NoError(t, g2.Undo())
+	assert.True(t, g2.Graph.EdgeExists(edge))
+	assert.Empty(t, g2.DanglingEdges())
+	assert.Empty(t, g2.Check())
+}
+
+func TestElementGraph_Undo_RemoveEdge(t *testing.T) {
+	g := NewElementGraph()
+
+	node := graph.NewNode(uuid.New(), []byte("hello"))
+	edge := graph.NewEdge(uuid.New(), node, node)
+	g.AddNode(node)
+	g.AddEdge(edge)
+
+	time.Sleep(1)
+	g.RemoveEdge(edge)
+
+	time.Sleep(1)
+	assert.NoError(t, g.Undo())
+	assert.True(t, g.Graph.EdgeExists(edge))
+
+	g.RegenerateGraph()
+	assert.True(t, g.Graph.EdgeExists(edge))
+}
+
+func TestElementGraph_Undo_Replicates(t *testing.T) {
+	g1 := NewElementGraph()
+	g2 := NewElementGraph()
+
+	node := graph.NewNode(uuid.New(), []byte("hello"))
+	g1.AddNode(node)
+	g2.Merge(g1)
+	assert.True(t, g2.Graph.NodeExists(node))
+
+	time.Sleep(1)
+	assert.NoError(t, g1.Undo())
+	g2.Merge(g1)
+	assert.False(t, g2.Graph.NodeExists(node))
+
+	time.Sleep(1)
+	assert.NoError(t, g1.Redo())
+	g2.Merge(g1)
+	assert.True(t, g2.Graph.NodeExists(node))
+}
+
+func TestElementGraph_Undo_ConflictWithMerge(t *testing.T) {
+	g1 := NewElementGraph()
+	g2 := NewElementGraph()
+
+	node := graph.NewNode(uuid.New(), []byte("hello"))
+	g1.AddNode(node)
+	g2.Merge(g1)
+
+	time.Sleep(1)
+	g2.RemoveNode(node)
+	g1.Merge(g2)
+
+	assert.Equal(t, ErrUndoConflict, g1.Undo())
+	assert.False(t, g1.CanUndo())
+	assert.False(t, g1.CanRedo())
+	assert.False(t, g1.Graph.NodeExists(node))
+}
+
+func TestElementGraph_Undo_AfterMerge(t *testing.T) {
+	g1 := NewElementGraph()
+	g2 := NewElementGraph()
+
+	node1 := graph.NewNode(uuid.New(), []byte("hello"))
+	node2 := graph.NewNode(uuid.New(), []byte("world"))
+	g1.AddNode(node1)
+	g2.AddNode(node2)
+	g1.Merge(g2)
+
+	time.Sleep(1)
+	assert.NoError(t, g1.Undo())
+	assert.False(t, g1.Graph.NodeExists(node1))
+	assert.True(t, g1.Graph.NodeExists(node2))
+}
+
+func TestElementGraph_Record_ClearsRedo(t *testing.T) {
+	g := NewElementGraph()
+
+	g.AddNode(graph.NewNode(uuid.New(), []byte("hello")))
+	assert.NoError(t, g.Undo())
+	assert.True(t, g.CanRedo())
+
+	g.AddNode(graph.NewNode(uuid.New(), []byte("world")))
+	assert.False(t, g.CanRedo())
+}
+
+func TestElementGraph_Undo_RemoveNodeRecordsAdd(t *testing.T) {
+	g := NewElementGraph(WithHistory())
+	node := graph.NewNode(uuid.New(), []byte("hello"))
+	g.AddNode(node)
+	time.Sleep(1)
+	g.RemoveNode(node)
+	time.Sleep(1)
+	assert.NoError(t, g.Undo())
+
+	kinds := make([]twoPSet.EventKind, 0)
+	for _, e := range g.NodeHistory(node.ID) {
+		kinds = append(kinds, e.Kind)
+	}
+	assert.Equal(t, []twoPSet.EventKind{twoPSet.Added, twoPSet.Removed, twoPSet.Added}, kinds)
+}
@@ -0,0 +1,74 @@
+package crdt
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/tauki/crdt/graph"
+	"github.com/tauki/crdt/twoPSet"
+)
+
+// ErrInvalidState is returned by Merge when the sets of the other replica are
+// malformed, e.g. an operation without payload, a payload stored under a
+// different id or an edge without endpoints.
+var ErrInvalidState = errors.New("invalid replica state")
+
+// validate checks that the sets of g can be merged and materialized without
+// further checks.
+func validate[N, E any](g *ElementGraphOf[N, E]) error {
+	if g == nil || g.NodeSet == nil || g.EdgeSet == nil {
+		return fmt.Errorf("%w: missing node or edge set", ErrInvalidState)
+	}
+
+	if err := validateSet(g.NodeSet, validNode[N, E]); err != nil {
+		return fmt.Errorf("%w: node set: %v", ErrInvalidState, err)
+	}
+	if err := validateSet(g.EdgeSet, validEdge[N, E]); err != nil {
+		return fmt.Errorf("%w: edge set: %v", ErrInvalidState, err)
+	}
+	return nil
+}
+
+// validateSet checks the payload of every operation of the set, including
+// the ones of its history.
+func validateSet[V any](set twoPSet.TwoPSetOf[V], valid func(uuid.UUID, V) error) error {
+	for _, ops := range []twoPSet.SetOf[V]{set.GetAddSet(), set.GetRemoveSet()} {
+		for k, op := range ops {
+			if err := valid(k, op.Payload); err != nil {
+				return err
+			}
+		}
+	}
+
+	for k, events := range set.GetHistory() {
+		for _, e := range events {
+			if err := valid(k, e.Payload); err != nil {
+				return fmt.Errorf("history: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func validNode[N, E any](id uuid.UUID, node *graph.NodeOf[N, E]) error {
+	if node == nil {
+		return fmt.Errorf("node %s has no payload", id)
+	}
+	if node.ID != id {
+		return fmt.Errorf("node %s is stored as %s", node.ID, id)
+	}
+	return nil
+}
+
+func validEdge[N, E any](id uuid.UUID, edge *graph.EdgeOf[N, E]) error {
+	if edge == nil {
+		return fmt.Errorf("edge %s has no payload", id)
+	}
+	if edge.ID != id {
+		return fmt.Errorf("edge %s is stored as %s", edge.ID, id)
+	}
+	if edge.From == nil || edge.To == nil || edge.From.ID == uuid.Nil || edge.To.ID == uuid.Nil {
+		return fmt.Errorf("edge %s is missing an endpoint", id)
+	}
+	return nil
+}
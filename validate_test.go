@@ -0,0 +1,104 @@
+package crdt
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/tauki/crdt/graph"
+	"github.com/tauki/crdt/twoPSet"
+	"testing"
+	"time"
+)
+
+func TestElementGraph_Merge_RejectsMalformedState(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(g *ElementGraph)
+	}{
+		{
+			name: "node without payload",
+			corrupt: func(g *ElementGraph) {
+				g.NodeSet.GetAddSet()[uuid.New()] = twoPSet.OPOf[*graph.Node]{Timestamp: time.Now()}
+			},
+		},
+		{
+			name: "node stored under another id",
+			corrupt: func(g *ElementGraph) {
+				g.NodeSet.GetAddSet()[uuid.New()] = twoPSet.OPOf[*graph.Node]{
+					Payload:   graph.NewNode(uuid.New(), nil),
+					Timestamp: time.Now(),
+				}
+			},
+		},
+		{
+			name: "removed edge without payload",
+			corrupt: func(g *ElementGraph) {
+				g.EdgeSet.GetRemoveSet()[uuid.New()] = twoPSet.OPOf[*graph.Edge]{Timestamp: time.Now()}
+			},
+		},
+		{
+			name: "edge without endpoint",
+			corrupt: func(g *ElementGraph) {
+				edge := graph.NewEdge(uuid.New(), graph.NewNode(uuid.New(), nil), nil)
+				g.EdgeSet.GetAddSet()[edge.ID] = twoPSet.OPOf[*graph.Edge]{Payload: edge, Timestamp: time.Now()}
+			},
+		},
+		{
+			name: "edge with nil endpoint id",
+			corrupt: func(g *ElementGraph) {
+				edge := graph.NewEdge(uuid.New(), graph.NewNode(uuid.New(), nil), graph.NewNode(uuid.Nil, nil))
+				g.EdgeSet.GetAddSet()[edge.ID] = twoPSet.OPOf[*graph.Edge]{Payload: edge, Timestamp: time.Now()}
+			},
+		},
+		{
+			name: "history event without payload",
+			corrupt: func(g *ElementGraph) {
+				g.EdgeSet.GetHistory()[uuid.New()] = []twoPSet.EventOf[*graph.Edge]{{Kind: twoPSet.Added}}
+			},
+		},
+		{
+			name: "missing sets",
+			corrupt: func(g *ElementGraph) {
+				g.NodeSet = nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := NewElementGraph(WithHistory())
+			node := graph.NewNode(uuid.New(), []byte("local"))
+			local.AddNode(node)
+
+			remote := NewElementGraph(WithHistory())
+			remote.AddNode(graph.NewNode(uuid.New(), []byte("remote")))
+			tt.corrupt(remote)
+
+			assert.ErrorIs(t, local.Merge(remote), ErrInvalidState)
+			assert.Len(t, local.NodeSet.GetAddSet(), 1)
+			assert.Len(t, local.NodeSet.GetHistory(), 1)
+			assert.Empty(t, local.EdgeSet.GetAddSet())
+			assert.Equal(t, 1, local.Graph.Len())
+			assert.True(t, local.Graph.NodeExists(node))
+		})
+	}
+}
+
+func TestElementGraph_Merge_RejectsNil(t *testing.T) {
+	g := NewElementGraph()
+	assert.ErrorIs(t, g.Merge(nil), ErrInvalidState)
+}
+
+func TestElementGraph_Merge_ValidState(t *testing.T) {
+	g1 := NewElementGraph()
+	g2 := NewElementGraph()
+
+	node1 := graph.NewNode(uuid.New(), []byte("a"))
+	node2 := graph.NewNode(uuid.New(), []byte("b"))
+	g2.AddNode(node1)
+	g2.AddNode(node2)
+	g2.AddEdge(graph.NewEdge(uuid.New(), node1, node2))
+	g2.RemoveNode(node2)
+
+	assert.NoError(t, g1.Merge(g2))
+	assert.Equal(t, 1, g1.Graph.Len())
+}